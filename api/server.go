@@ -0,0 +1,200 @@
+package api
+
+import (
+	"chia-miner/miner"
+	"chia-miner/pkg/metrics"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MiningInfo current challenge of the miner
+type MiningInfo struct {
+	Height         uint32 `json:"height"`
+	Challenge      string `json:"challenge"`
+	Difficulty     uint64 `json:"difficulty"`
+	Epoch          int64  `json:"epoch"`
+	ScanIterations int64  `json:"scan_iterations"`
+	ReceiveTime    int64  `json:"receive_time"`
+	FilterBits     int    `json:"filter_bits"`
+	ServerTime     int64  `json:"server_time"`
+}
+
+// Status summary of the running miner
+type Status struct {
+	MiningInfo *MiningInfo          `json:"mining_info"`
+	Plots      int                  `json:"plots"`
+	Capacity   uint64               `json:"capacity"`
+	Spaces     []*miner.SpaceStatus `json:"spaces"`
+}
+
+// Start listens on address and serves the status and control api in the background
+func Start(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/status", handleStatus)
+	mux.HandleFunc("/api/mininginfo", handleMiningInfo)
+	mux.HandleFunc("/api/spaces", handleSpaces)
+	mux.HandleFunc("/api/submissions", handleSubmissions)
+	mux.HandleFunc("/api/submissions/stats", handleSubmitStats)
+	mux.HandleFunc("/api/keys", handleKeys)
+	mux.HandleFunc("/api/harvesters", handleHarvesters)
+	mux.HandleFunc("/api/nodes", handleNodes)
+	mux.HandleFunc("/api/pool", handlePool)
+	mux.HandleFunc("/api/reload", handleReload)
+	mux.HandleFunc("/api/rescan", handleRescan)
+	mux.Handle("/metrics", metrics.Handler())
+
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			logrus.Errorf("api server stopped, error %v", err)
+		}
+	}()
+	logrus.Infof("api server listening on %v", listener.Addr())
+	return nil
+}
+
+func getMiningInfo() *MiningInfo {
+	info := miner.GetMiner().GetMiningInfo()
+	if info == nil {
+		return nil
+	}
+	return &MiningInfo{
+		Height:         info.Height,
+		Challenge:      hex.EncodeToString(info.Challenge),
+		Difficulty:     info.Difficulty,
+		Epoch:          info.Epoch,
+		ScanIterations: info.ScanIterations,
+		ReceiveTime:    info.ReceiveTime,
+		FilterBits:     info.FilterBits,
+		ServerTime:     info.ServerTime,
+	}
+}
+
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodGet) {
+		return
+	}
+	status := &Status{
+		MiningInfo: getMiningInfo(),
+		Spaces:     miner.GetMiner().GetSpaceStatus(),
+	}
+	for _, space := range status.Spaces {
+		status.Plots += space.Plots
+		status.Capacity += space.Capacity
+	}
+	writeJson(w, http.StatusOK, status)
+}
+
+func handleHarvesters(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodGet) {
+		return
+	}
+	writeJson(w, http.StatusOK, miner.GetMiner().GetHarvesters())
+}
+
+func handleMiningInfo(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodGet) {
+		return
+	}
+	info := getMiningInfo()
+	if info == nil {
+		writeError(w, http.StatusServiceUnavailable, "no mining info received yet")
+		return
+	}
+	writeJson(w, http.StatusOK, info)
+}
+
+func handleSpaces(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodGet) {
+		return
+	}
+	writeJson(w, http.StatusOK, miner.GetMiner().GetSpaceStatus())
+}
+
+func handleSubmissions(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodGet) {
+		return
+	}
+	writeJson(w, http.StatusOK, miner.GetMiner().GetSubmissions())
+}
+
+func handleSubmitStats(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodGet) {
+		return
+	}
+	writeJson(w, http.StatusOK, miner.GetMiner().GetSubmitStats())
+}
+
+func handleKeys(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodGet) {
+		return
+	}
+	writeJson(w, http.StatusOK, miner.GetMiner().AuditKeys())
+}
+
+func handleNodes(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodGet) {
+		return
+	}
+	writeJson(w, http.StatusOK, miner.GetJsonRpc().GetEndpoints())
+}
+
+func handlePool(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodGet) {
+		return
+	}
+	stats := miner.GetMiner().GetPoolStats()
+	if stats == nil {
+		writeError(w, http.StatusNotFound, "pooling is not configured")
+		return
+	}
+	writeJson(w, http.StatusOK, stats)
+}
+
+func handleReload(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodPost) {
+		return
+	}
+	miner.GetMiner().ReloadPlots()
+	writeJson(w, http.StatusOK, miner.GetMiner().GetSpaceStatus())
+}
+
+func handleRescan(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodPost) {
+		return
+	}
+	if err := miner.GetMiner().Rescan(); err != nil {
+		writeError(w, http.StatusServiceUnavailable, "no mining info received yet")
+		return
+	}
+	writeJson(w, http.StatusOK, getMiningInfo())
+}
+
+func checkMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return false
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJson(w, code, map[string]string{"error": message})
+}
+
+func writeJson(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Debugf("api write response error %v", err)
+	}
+}
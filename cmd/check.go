@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"chia-miner/pkg/chiapos"
+	config2 "chia-miner/pkg/config"
+	"chia-miner/utils"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// plotCheck result of checking one plot
+type plotCheck struct {
+	file      string
+	k         uint32
+	format    string
+	openError error
+	proofs    int
+	invalid   int
+	expected  int
+	hasKey    bool
+}
+
+// runCheck runs random challenges through every plot and validates the proofs
+func runCheck(cfg *config2.Config, args []string) error {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	challenges := flags.Int("n", 30, "number of random challenges per plot")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *challenges <= 0 {
+		return fmt.Errorf("invalid number of challenges %v", *challenges)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "FILE\tK\tFORMAT\tPROOFS\tEXPECTED\tRATIO\tINVALID\tFARMER KEY\tSTATUS")
+	var total, bad int
+	for _, path := range cfg.Path {
+		for _, fileInfo := range utils.GetFileList(path, ".plot") {
+			result := checkPlot(cfg, fileInfo.FilePath, *challenges)
+			total++
+			if result.openError != nil {
+				bad++
+				fmt.Fprintf(w, "%v\t-\t%v\t-\t-\t-\t-\t-\tskipped, %v: %v\n", result.file, result.format,
+					chiapos.SkipReason(result.openError), result.openError)
+				continue
+			}
+			status := "ok"
+			switch {
+			case result.invalid > 0:
+				status = "invalid proofs"
+			case result.proofs == 0:
+				status = "no proofs"
+			case !result.hasKey:
+				status = "farmer key not configured"
+			}
+			if status != "ok" {
+				bad++
+			}
+			keyStatus := "missing"
+			if result.hasKey {
+				keyStatus = "configured"
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%.2f\t%v\t%v\t%v\n", result.file, result.k, result.format, result.proofs, result.expected,
+				float64(result.proofs)/float64(result.expected), result.invalid, keyStatus, status)
+		}
+	}
+	w.Flush()
+	fmt.Printf("%v plots checked, %v with problems\n", total, bad)
+	return nil
+}
+
+func checkPlot(cfg *config2.Config, file string, challenges int) *plotCheck {
+	result := &plotCheck{file: file, expected: challenges}
+	if format, err := chiapos.ReadPlotFormat(file); err == nil {
+		result.format = format.String()
+	} else {
+		result.format = "-"
+	}
+	f, err := chiapos.Open(file)
+	if err != nil {
+		result.openError = err
+		return result
+	}
+	defer f.Close()
+	result.k = f.GetSize()
+	fPubKey, _ := f.GetFarmerPublicKey()
+	_, result.hasKey = cfg.FarmerKey[fPubKey]
+
+	challenge := make([]byte, 32)
+	for i := 0; i < challenges; i++ {
+		if _, err := rand.Read(challenge); err != nil {
+			result.openError = err
+			return result
+		}
+		qualities, _ := f.GetQualitiesForChallenge(challenge)
+		for index, quality := range qualities {
+			proof, ok := f.GetFullProof(challenge, index)
+			if !ok {
+				result.invalid++
+				continue
+			}
+			verified, ok := chiapos.VerifyProof(f.GetId(), result.k, challenge, proof)
+			if !ok || !bytes.Equal(verified, quality) {
+				result.invalid++
+				continue
+			}
+			result.proofs++
+		}
+	}
+	return result
+}
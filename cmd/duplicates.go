@@ -0,0 +1,61 @@
+package main
+
+import (
+	"chia-miner/miner"
+	"chia-miner/pkg/chiapos"
+	config2 "chia-miner/pkg/config"
+	"chia-miner/utils"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// runDuplicates lists the plots found more than once under the configured
+// paths and which copy the miner farms
+func runDuplicates(cfg *config2.Config) error {
+	copies := make(map[string][]string)
+	var ids []string
+	var total int
+	for _, path := range cfg.Path {
+		for _, fileInfo := range utils.GetFileList(path, ".plot") {
+			// the miner does not load compressed plots or plots without a valid memo
+			header, err := chiapos.ReadPlotHeader(fileInfo.FilePath)
+			if err != nil || header.Format.Compressed() {
+				continue
+			}
+			if _, err := chiapos.ParseMemo(header.Memo); err != nil {
+				continue
+			}
+			total++
+			id := hex.EncodeToString(header.Id)
+			if _, ok := copies[id]; !ok {
+				ids = append(ids, id)
+			}
+			copies[id] = append(copies[id], fileInfo.FilePath)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "PLOT ID\tFILE\tSTATUS")
+	var duplicates int
+	for _, id := range ids {
+		files := copies[id]
+		if len(files) < 2 {
+			continue
+		}
+		sort.Slice(files, func(i, j int) bool {
+			return miner.PreferredPlotFile(cfg, files[i], files[j])
+		})
+		fmt.Fprintf(w, "%v\t%v\tfarmed\n", id, files[0])
+		for _, file := range files[1:] {
+			fmt.Fprintf(w, "%v\t%v\tduplicate\n", id, file)
+			duplicates++
+		}
+	}
+	w.Flush()
+	fmt.Printf("%v plots checked, %v duplicates not farmed\n", total, duplicates)
+	return nil
+}
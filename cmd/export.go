@@ -0,0 +1,85 @@
+package main
+
+import (
+	export "chia-miner/export"
+	config2 "chia-miner/pkg/config"
+	"chia-miner/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// readMnemonic prompts for a 24 words mnemonic until one is entered
+func readMnemonic() (string, error) {
+	for {
+		mnemonic, err := readSecret("Please enter the chia mnemonic: ")
+		if err != nil {
+			return "", err
+		}
+		words := strings.Fields(mnemonic)
+		if len(words) == 24 {
+			return strings.Join(words, " "), nil
+		}
+		fmt.Println("Please enter chia mnemonics, 24 words separated by spaces")
+	}
+}
+
+// runExport derives the keys of a mnemonic and writes them as text, json or into the keystore
+func runExport(format string, wallets int, out, prefix string) error {
+	if format != "text" && format != "json" && format != "keystore" {
+		return fmt.Errorf("unknown export format %v", format)
+	}
+	mnemonic, err := readMnemonic()
+	if err != nil {
+		return err
+	}
+	keys, err := export.ExportKeys(mnemonic, wallets, prefix)
+	if err != nil {
+		return err
+	}
+
+	if format == "keystore" {
+		// the config is optional, it only locates the keystore and its passphrase file
+		cfg := &config2.Config{}
+		if err := utils.LoadConfigFromFile(*config, cfg); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if out != "" {
+			cfg.Keystore.File = out
+		}
+		return importKey(cfg, keys.Farmer.PrivateKey, fmt.Sprint(keys.Fingerprint))
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "" {
+		file, err := os.OpenFile(out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(keys)
+	}
+
+	fmt.Fprintln(w, "Fingerprint:", keys.Fingerprint)
+	fmt.Fprintln(w, "Master public key:", keys.MasterPublicKey)
+	fmt.Fprintln(w, "Farmer public key:", keys.Farmer.PublicKey)
+	fmt.Fprintln(w, "Farmer private key:", keys.Farmer.PrivateKey)
+	fmt.Fprintln(w, "Pool public key:", keys.Pool.PublicKey)
+	fmt.Fprintln(w, "Pool private key:", keys.Pool.PrivateKey)
+	fmt.Fprintln(w, "Local public key:", keys.Local.PublicKey)
+	fmt.Fprintln(w, "Local private key:", keys.Local.PrivateKey)
+	for _, wallet := range keys.Wallets {
+		fmt.Fprintf(w, "Wallet %v public key: %v\n", wallet.Index, wallet.PublicKey)
+		fmt.Fprintf(w, "Wallet %v private key: %v\n", wallet.Index, wallet.PrivateKey)
+		fmt.Fprintf(w, "Wallet %v address: %v\n", wallet.Index, wallet.Address)
+		fmt.Fprintf(w, "Wallet %v observer address: %v\n", wallet.Index, wallet.ObserverAddress)
+	}
+	return nil
+}
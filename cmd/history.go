@@ -0,0 +1,82 @@
+package main
+
+import (
+	config2 "chia-miner/pkg/config"
+	"chia-miner/pkg/history"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+func parseTime(value string, defaultTime time.Time) (time.Time, error) {
+	if value == "" {
+		return defaultTime, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %v, use YYYY-MM-DD, YYYY-MM-DD hh:mm:ss or RFC3339", value)
+}
+
+// runHistory queries the history database: wins, deadlines or failures
+func runHistory(cfg *config2.Config, query, fromValue, toValue string) error {
+	now := time.Now()
+	from, err := parseTime(fromValue, now.AddDate(0, 0, -7))
+	if err != nil {
+		return err
+	}
+	to, err := parseTime(toValue, now)
+	if err != nil {
+		return err
+	}
+
+	db, err := history.Open(cfg.GetHistoryFile())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+	switch query {
+	case "wins":
+		list, err := db.Wins(from, to)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "TIME\tHEIGHT\tPLOT ID\tREQUIRED ITERS\tMESSAGE")
+		for _, s := range list {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", s.CreatedAt.Format(timeLayouts[1]), s.Height, s.PlotId, s.RequiredIters, s.Message)
+		}
+	case "deadlines":
+		list, err := db.BestDeadlines(from, to)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "PLOT ID\tBEST DEADLINE\tCANDIDATES\tFILE")
+		for _, d := range list {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", d.PlotId, d.Deadline, d.Candidates, d.PlotFile)
+		}
+	case "failures":
+		list, err := db.Failures(from, to)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "TIME\tHEIGHT\tPLOT ID\tATTEMPTS\tCODE\tERROR")
+		for _, s := range list {
+			reason := s.Error
+			if reason == "" {
+				reason = "rejected: " + s.Message
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", s.CreatedAt.Format(timeLayouts[1]), s.Height, s.PlotId, s.Attempts, s.Code, reason)
+		}
+	default:
+		return fmt.Errorf("unknown history query %v, use wins, deadlines or failures", query)
+	}
+	return nil
+}
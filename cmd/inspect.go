@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"chia-miner/pkg/bls"
+	"chia-miner/pkg/chiapos"
+	config2 "chia-miner/pkg/config"
+	"chia-miner/utils"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// plotInspection header details of one plot file
+type plotInspection struct {
+	File                         string `json:"file"`
+	Id                           string `json:"id,omitempty"`
+	K                            uint32 `json:"k,omitempty"`
+	Format                       string `json:"format,omitempty"`
+	PoolPublicKey                string `json:"pool_public_key,omitempty"`
+	PoolContractPuzzleHash       string `json:"pool_contract_puzzle_hash,omitempty"`
+	FarmerPublicKey              string `json:"farmer_public_key,omitempty"`
+	LocalMasterSecretFingerprint uint32 `json:"local_master_secret_fingerprint,omitempty"`
+	FileSize                     int64  `json:"file_size"`
+	ExpectedFileSize             uint64 `json:"expected_file_size,omitempty"`
+	NameId                       string `json:"name_id,omitempty"`
+	NameIdCheck                  string `json:"name_id_check"` // match, mismatch or no id in name
+	SkipReason                   string `json:"skip_reason,omitempty"`
+	Error                        string `json:"error,omitempty"`
+}
+
+// runInspect prints the header details of the plots given as files or
+// directories, the configured plot paths when none are given
+func runInspect(args []string) error {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown inspect format %v", *format)
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		cfg := &config2.Config{}
+		if err := utils.LoadConfigFromFile(*config, cfg); err != nil {
+			return err
+		}
+		paths = cfg.Path
+	}
+
+	results := make([]*plotInspection, 0)
+	for _, path := range paths {
+		// directories ending with /* are listed recursively
+		info, err := os.Stat(strings.TrimSuffix(path, "/*"))
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			results = append(results, inspectPlot(path))
+			continue
+		}
+		for _, fileInfo := range utils.GetFileList(path, ".plot") {
+			results = append(results, inspectPlot(fileInfo.FilePath))
+		}
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+	for i, result := range results {
+		if i > 0 {
+			fmt.Println()
+		}
+		printInspection(result)
+	}
+	return nil
+}
+
+// inspectPlot reads the header of a plot, compressed plots included
+func inspectPlot(file string) *plotInspection {
+	result := &plotInspection{File: file, NameIdCheck: "no id in name"}
+	if info, err := os.Stat(file); err == nil {
+		result.FileSize = info.Size()
+	}
+	header, err := chiapos.ReadPlotHeader(file)
+	if err != nil {
+		result.SkipReason = chiapos.SkipReason(err)
+		result.Error = err.Error()
+		return result
+	}
+	result.K = header.Format.K
+	result.Format = header.Format.String()
+	result.ExpectedFileSize = chiapos.PlotFileSize(header.Format.K)
+	result.Id = hex.EncodeToString(header.Id)
+	if header.Format.Compressed() {
+		result.SkipReason = chiapos.SkipCompressed
+		result.Error = fmt.Sprintf("compression level %d: %v", header.Format.CompressionLevel, chiapos.ErrCompressedPlot)
+	}
+
+	if memo, err := chiapos.ParseMemo(header.Memo); err == nil {
+		if memo.IsPoolContract() {
+			result.PoolContractPuzzleHash = hex.EncodeToString(memo.PoolContractPuzzleHash)
+		} else {
+			result.PoolPublicKey = hex.EncodeToString(memo.PoolPublicKey)
+		}
+		result.FarmerPublicKey = hex.EncodeToString(memo.FarmerPublicKey)
+		if localMasterKey, err := bls.PrivateKeyFromBytes(memo.LocalMasterSecret); err == nil {
+			result.LocalMasterSecretFingerprint = localMasterKey.Public().(*bls.PublicKey).GetFingerprint()
+		}
+	} else {
+		result.SkipReason = chiapos.SkipReason(err)
+		result.Error = err.Error()
+	}
+
+	if nameId := plotNameId(file); nameId != nil {
+		result.NameId = hex.EncodeToString(nameId)
+		result.NameIdCheck = "mismatch"
+		if bytes.Equal(nameId, header.Id) {
+			result.NameIdCheck = "match"
+		}
+	}
+	return result
+}
+
+// plotNameId returns the plot id at the end of a plot-k32-<date>-<id>.plot file name
+func plotNameId(file string) []byte {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	name = name[strings.LastIndex(name, "-")+1:]
+	if len(name) != 2*chiapos.IdLen {
+		return nil
+	}
+	id, err := hex.DecodeString(name)
+	if err != nil {
+		return nil
+	}
+	return id
+}
+
+func printInspection(result *plotInspection) {
+	fmt.Println("File:", result.File)
+	if result.Format != "" {
+		fmt.Println("Plot id:", result.Id)
+		fmt.Println("K size:", result.K)
+		fmt.Println("Format:", result.Format)
+		if result.PoolContractPuzzleHash != "" {
+			fmt.Println("Pool contract puzzle hash:", result.PoolContractPuzzleHash)
+		} else if result.PoolPublicKey != "" {
+			fmt.Println("Pool public key:", result.PoolPublicKey)
+		}
+		if result.FarmerPublicKey != "" {
+			fmt.Println("Farmer public key:", result.FarmerPublicKey)
+			fmt.Println("Local master secret fingerprint:", result.LocalMasterSecretFingerprint)
+		}
+		fmt.Printf("File size: %v bytes, expected %v bytes for k%v (%.1f%%)\n", result.FileSize,
+			result.ExpectedFileSize, result.K, float64(result.FileSize)*100/float64(result.ExpectedFileSize))
+	} else {
+		fmt.Printf("File size: %v bytes\n", result.FileSize)
+	}
+	if result.NameId != "" {
+		fmt.Printf("File name id: %v (%v)\n", result.NameId, result.NameIdCheck)
+	} else {
+		fmt.Println("File name id:", result.NameIdCheck)
+	}
+	if result.Error != "" {
+		fmt.Printf("Skipped: %v: %v\n", result.SkipReason, result.Error)
+	}
+}
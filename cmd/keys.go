@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	export "chia-miner/export"
+	"chia-miner/pkg/bls"
+	config2 "chia-miner/pkg/config"
+	"chia-miner/pkg/keystore"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// passphraseEnv environment variable holding the keystore passphrase
+const passphraseEnv = "MINER_KEYSTORE_PASSPHRASE"
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// readSecret reads a line from the terminal without echo, or from stdin if it is not a terminal
+func readSecret(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	fd := int(os.Stdin.Fd())
+	if terminal.IsTerminal(fd) {
+		data, err := terminal.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return strings.TrimSpace(string(data)), err
+	}
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// getPassphrase returns the keystore passphrase from the environment, the
+// passphrase file or a prompt; confirm asks twice when a new keystore is created
+func getPassphrase(cfg *config2.Config, confirm bool) ([]byte, error) {
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	if cfg.Keystore.PassphraseFile != "" {
+		data, err := ioutil.ReadFile(cfg.Keystore.PassphraseFile)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strings.TrimRight(string(data), "\r\n")), nil
+	}
+	passphrase, err := readSecret("Keystore passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if confirm {
+		again, err := readSecret("Repeat passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if again != passphrase {
+			return nil, fmt.Errorf("passphrases do not match")
+		}
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("empty passphrase")
+	}
+	return []byte(passphrase), nil
+}
+
+func openKeystore(cfg *config2.Config) (*keystore.Keystore, error) {
+	path := cfg.GetKeystoreFile()
+	passphrase, err := getPassphrase(cfg, !keystore.Exists(path))
+	if err != nil {
+		return nil, err
+	}
+	return keystore.Open(path, passphrase)
+}
+
+// loadKeystore unlocks the keystore if it exists and adds its keys to farmerKey
+func loadKeystore(cfg *config2.Config) error {
+	path := cfg.GetKeystoreFile()
+	if !keystore.Exists(path) {
+		return nil
+	}
+	ks, err := openKeystore(cfg)
+	if err != nil {
+		return err
+	}
+	for _, entry := range ks.List() {
+		cfg.FarmerKey[entry.PublicKey] = entry.PrivateKey
+	}
+	return nil
+}
+
+// runKeys manages the keystore: import-mnemonic, import-hex, list and remove
+func runKeys(cfg *config2.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: keys import-mnemonic|import-hex|list|remove <fingerprint>")
+	}
+	switch args[0] {
+	case "import-mnemonic":
+		mnemonic, err := readSecret("Please enter the chia mnemonic: ")
+		if err != nil {
+			return err
+		}
+		if len(strings.Fields(mnemonic)) != 24 {
+			return fmt.Errorf("please enter chia mnemonics, 24 words separated by spaces")
+		}
+		_, privateKeyHex, err := export.GetFarmerPrivateKeyByMnemonic(strings.Join(strings.Fields(mnemonic), " "))
+		if err != nil {
+			return err
+		}
+		return importKey(cfg, privateKeyHex, label(args))
+	case "import-hex":
+		privateKeyHex, err := readSecret("Please enter the farmer private key: ")
+		if err != nil {
+			return err
+		}
+		return importKey(cfg, privateKeyHex, label(args))
+	case "list":
+		ks, err := openKeystore(cfg)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		defer w.Flush()
+		fmt.Fprintln(w, "FINGERPRINT\tLABEL\tFARMER PUBLIC KEY")
+		for _, entry := range ks.List() {
+			fmt.Fprintf(w, "%v\t%v\t%v\n", entry.Fingerprint, entry.Label, entry.PublicKey)
+		}
+		return nil
+	case "remove":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: keys remove <fingerprint>")
+		}
+		fingerprint, err := strconv.ParseUint(args[1], 10, 32)
+		if err != nil {
+			return err
+		}
+		ks, err := openKeystore(cfg)
+		if err != nil {
+			return err
+		}
+		if err := ks.Remove(uint32(fingerprint)); err != nil {
+			return err
+		}
+		if err := ks.Save(); err != nil {
+			return err
+		}
+		fmt.Println("Removed key", fingerprint)
+		return nil
+	default:
+		return fmt.Errorf("unknown keys command %v", args[0])
+	}
+}
+
+// label returns the optional label argument of the import commands
+func label(args []string) string {
+	if len(args) > 1 {
+		return args[1]
+	}
+	return ""
+}
+
+func importKey(cfg *config2.Config, privateKeyHex, label string) error {
+	privateKey, err := bls.PrivateKeyFromHex(privateKeyHex)
+	if err != nil {
+		return err
+	}
+	ks, err := openKeystore(cfg)
+	if err != nil {
+		return err
+	}
+	entry, err := ks.Add(privateKey, label)
+	if err != nil {
+		return err
+	}
+	if err := ks.Save(); err != nil {
+		return err
+	}
+	fmt.Println("Imported key", entry.Fingerprint, "farmer public key", entry.PublicKey)
+	return nil
+}
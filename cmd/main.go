@@ -1,11 +1,13 @@
 package main
 
 import (
+	"chia-miner/api"
 	"chia-miner/app"
 	export "chia-miner/export"
 	"chia-miner/miner"
 	config2 "chia-miner/pkg/config"
 	"chia-miner/pkg/log"
+	"chia-miner/pkg/metrics"
 	"chia-miner/utils"
 	"flag"
 	"fmt"
@@ -17,44 +19,149 @@ import (
 )
 
 var config = flag.String("config", "config.yaml", "configuration file")
-var exportFarmer = flag.Bool("export", false, "export farmer private")
+var exportFarmer = flag.Bool("export", false, "export farmer, pool, local and wallet keys of a mnemonic")
+var exportFormat = flag.String("format", "text", "export format: text, json or keystore")
+var exportWallets = flag.Int("wallets", 1, "number of wallet keys to export")
+var exportOut = flag.String("out", "", "export output file, the keystore file for the keystore format")
+var exportPrefix = flag.String("prefix", export.DefaultAddressPrefix, "wallet address prefix")
+var historyQuery = flag.String("history", "", "query the history database: wins, deadlines or failures")
+var historyFrom = flag.String("from", "", "history query start time, default 7 days ago")
+var historyTo = flag.String("to", "", "history query end time, default now")
 
 func main() {
 	flag.Parse()
 	if *exportFarmer {
-		fmt.Println("Please enter the chia mnemonic:")
-		for {
-			mnemonic := utils.GetInput()
-			// 24 mnemonic words
-			if len(strings.Split(strings.TrimSpace(mnemonic), " ")) != 24 {
-				fmt.Println("Please enter chia mnemonics, separated by spaces")
-				continue
-			}
-			farmerPublicKey, farmerPrivateKey, err := export.GetFarmerPrivateKeyByMnemonic(mnemonic)
-			if err != nil {
-				fmt.Println("export failed ~ ", err)
-				return
-			}
-			fmt.Println("Farmer public key:", farmerPublicKey)
-			fmt.Println("Farmer private key:", farmerPrivateKey)
+		if err := runExport(*exportFormat, *exportWallets, *exportOut, *exportPrefix); err != nil {
+			fmt.Println("export failed ~ ", err)
+		}
+		return
+	}
+	// plot paths can be given on the command line, the config is optional
+	if flag.Arg(0) == "inspect" {
+		if err := runInspect(flag.Args()[1:]); err != nil {
+			fmt.Println("inspect failed ~ ", err)
+			os.Exit(1)
+		}
+		return
+	}
+	var cfg = &config2.Config{}
+	if err := utils.LoadConfigFromFile(*config, cfg); err != nil {
+		fmt.Printf("load config fail, error %v", err)
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Printf("load config fail, error %v", err)
+		return
+	}
+	if *historyQuery != "" {
+		if err := runHistory(cfg, *historyQuery, *historyFrom, *historyTo); err != nil {
+			fmt.Println("history query failed ~ ", err)
+		}
+		return
+	}
+	switch flag.Arg(0) {
+	case "":
+	case "check":
+		if err := loadFarmerKeys(cfg); err != nil {
+			fmt.Println("load keystore failed ~ ", err)
 			return
 		}
+		if err := runCheck(cfg, flag.Args()[1:]); err != nil {
+			fmt.Println("check failed ~ ", err)
+		}
+		return
+	case "verify-keys":
+		if err := runVerifyKeys(cfg, flag.Args()[1:]); err != nil {
+			fmt.Println("verify keys failed ~ ", err)
+		}
+		return
+	case "duplicates":
+		if err := runDuplicates(cfg); err != nil {
+			fmt.Println("duplicates failed ~ ", err)
+		}
+		return
+	case "keys":
+		if err := runKeys(cfg, flag.Args()[1:]); err != nil {
+			fmt.Println("keys failed ~ ", err)
+		}
+		return
+	default:
+		fmt.Println("unknown command", flag.Arg(0))
+		return
 	}
+
 	fmt.Println("QitChain miner")
 	fmt.Println("Version: ", app.Version, app.BuildVersion)
 	fmt.Println("Build time: ", app.BuildTime)
 
-	var cfg = &config2.Config{}
-	if err := utils.LoadConfigFromFile(*config, cfg); err != nil {
-		fmt.Printf("load config fail, error %v", err)
+	switch cfg.Role {
+	case "", "miner", "farmer":
+	case "harvester":
+		runHarvester(cfg)
+		return
+	default:
+		fmt.Println("unknown role", cfg.Role)
 		return
 	}
 
-	if cfg.Rpc.Url == "" {
-		cfg.Rpc.Url = "http://localhost:3332"
+	for _, endpoint := range cfg.GetRpcEndpoints() {
+		fmt.Println("Wallet address", endpoint.Url)
 	}
-	fmt.Println("Wallet address", cfg.Rpc.Url)
 
+	if err := loadFarmerKeys(cfg); err != nil {
+		fmt.Println("load keystore failed ~ ", err)
+		return
+	}
+
+	log.InitLog(cfg.Log.Level, cfg.Log.File)
+	if err := miner.GetMiner().Start(cfg); err != nil {
+		logrus.Errorf("Failed to start miner %v", err)
+		return
+	}
+
+	if cfg.Api.Listen != "" {
+		if err := api.Start(cfg.Api.Listen); err != nil {
+			logrus.Errorf("Failed to start api server %v", err)
+		}
+	}
+	if cfg.Metrics.Listen != "" {
+		if err := metrics.Start(cfg.Metrics.Listen); err != nil {
+			logrus.Errorf("Failed to start metrics server %v", err)
+		}
+	}
+
+	waitSignal()
+}
+
+// runHarvester scans the local plots for a remote farmer, no node or farmer keys are needed
+func runHarvester(cfg *config2.Config) {
+	if cfg.Harvester.Farmer == "" {
+		fmt.Println("harvester.farmer is not configured")
+		return
+	}
+	log.InitLog(cfg.Log.Level, cfg.Log.File)
+	if err := miner.NewHarvester(cfg).Start(); err != nil {
+		logrus.Errorf("Failed to start harvester %v", err)
+		return
+	}
+	if cfg.Metrics.Listen != "" {
+		if err := metrics.Start(cfg.Metrics.Listen); err != nil {
+			logrus.Errorf("Failed to start metrics server %v", err)
+		}
+	}
+	waitSignal()
+}
+
+func waitSignal() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGINT, syscall.SIGKILL, syscall.SIGTERM)
+	s := <-c
+	logrus.Infof("Received signal %v", s)
+}
+
+// loadFarmerKeys derives the farmer keys of farmerPrivateKey into farmerKey
+// and adds the keys of the keystore
+func loadFarmerKeys(cfg *config2.Config) error {
 	if cfg.FarmerKey == nil {
 		cfg.FarmerKey = make(map[string]string)
 	}
@@ -77,12 +184,5 @@ func main() {
 			}
 		}
 	}
-
-	log.InitLog(cfg.Log.Level, cfg.Log.File)
-	miner.GetMiner().Start(cfg)
-
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, syscall.SIGINT, syscall.SIGKILL, syscall.SIGTERM)
-	s := <-c
-	logrus.Infof("Received signal %v", s)
+	return loadKeystore(cfg)
 }
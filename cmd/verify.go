@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	export "chia-miner/export"
+	"chia-miner/pkg/bls"
+	"chia-miner/pkg/chiapos"
+	config2 "chia-miner/pkg/config"
+	"chia-miner/utils"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// ownerKeys public keys plots are compared against, poolPk is nil for keystore entries
+type ownerKeys struct {
+	farmerPk *bls.PublicKey
+	poolPk   []byte
+}
+
+// plotOwner result of comparing one plot with the owner keys
+type plotOwner struct {
+	file   string
+	farmer string
+	pool   string
+	plotId string
+}
+
+// runVerifyKeys reports which plots were created with the keys of a mnemonic or keystore entry
+func runVerifyKeys(cfg *config2.Config, args []string) error {
+	flags := flag.NewFlagSet("verify-keys", flag.ExitOnError)
+	fingerprint := flags.Uint("fingerprint", 0, "use the farmer key of a keystore entry instead of a mnemonic")
+	onlyMatching := flags.Bool("matching", false, "list only plots created with the farmer key")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	keys, err := getOwnerKeys(cfg, uint32(*fingerprint))
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "FILE\tFARMER KEY\tPOOL KEY\tPLOT ID")
+	var total, matched, invalid int
+	for _, path := range cfg.Path {
+		for _, fileInfo := range utils.GetFileList(path, ".plot") {
+			result := verifyPlotOwner(keys, fileInfo.FilePath)
+			total++
+			if result.farmer == "match" {
+				matched++
+			}
+			if result.plotId == "mismatch" {
+				invalid++
+			}
+			if *onlyMatching && result.farmer != "match" {
+				continue
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", result.file, result.farmer, result.pool, result.plotId)
+		}
+	}
+	w.Flush()
+	fmt.Printf("%v plots checked, %v created with the farmer key, %v with a mismatching plot id\n", total, matched, invalid)
+	return nil
+}
+
+// getOwnerKeys derives the farmer and pool public keys of a mnemonic, or reads the farmer key of a keystore entry
+func getOwnerKeys(cfg *config2.Config, fingerprint uint32) (*ownerKeys, error) {
+	if fingerprint != 0 {
+		ks, err := openKeystore(cfg)
+		if err != nil {
+			return nil, err
+		}
+		entry, err := ks.Get(fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		farmerPk, err := bls.PublicKeyFromHex(entry.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		return &ownerKeys{farmerPk: farmerPk}, nil
+	}
+
+	mnemonic, err := readMnemonic()
+	if err != nil {
+		return nil, err
+	}
+	masterKey, err := export.GetMasterKeyByMnemonic(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+	poolPk, _ := export.CreatePoolKey(masterKey).Public().(*bls.PublicKey).MarshalBinary()
+	return &ownerKeys{
+		farmerPk: export.CreateFarmerKey(masterKey).Public().(*bls.PublicKey),
+		poolPk:   poolPk,
+	}, nil
+}
+
+func verifyPlotOwner(keys *ownerKeys, file string) *plotOwner {
+	result := &plotOwner{file: file, farmer: "-", pool: "-", plotId: "-"}
+	// only the header is needed, no prover is opened
+	header, err := chiapos.ReadPlotHeader(file)
+	if err != nil {
+		result.farmer = fmt.Sprintf("open failed: %v", err)
+		return result
+	}
+	memo, err := chiapos.ParseMemo(header.Memo)
+	if err != nil {
+		result.farmer = fmt.Sprintf("invalid memo: %v", err)
+		return result
+	}
+
+	farmerPk, _ := keys.farmerPk.MarshalBinary()
+	if bytes.Equal(memo.FarmerPublicKey, farmerPk) {
+		result.farmer = "match"
+	}
+	contract := memo.IsPoolContract()
+	switch {
+	case contract:
+		result.pool = "contract"
+	case keys.poolPk != nil && bytes.Equal(memo.PoolPublicKey, keys.poolPk):
+		result.pool = "match"
+	}
+	if result.farmer != "match" {
+		return result
+	}
+
+	// recompute the plot id from the local master secret in the memo and the farmer key
+	result.plotId = "mismatch"
+	localPk, err := export.GetLocalPublicKey(memo.LocalMasterSecret)
+	if err != nil {
+		return result
+	}
+	plotPk, err := export.GetPlotPublicKey(localPk, keys.farmerPk, contract)
+	if err != nil {
+		return result
+	}
+	if bytes.Equal(export.GetPlotId(memo.Pool(), plotPk), header.Id) {
+		result.plotId = "ok"
+	}
+	return result
+}
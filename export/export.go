@@ -7,8 +7,7 @@ import (
 )
 
 func CreateFarmerKey(masterKey *bls.PrivateKey) *bls.PrivateKey {
-	coinType := int(0x800020fc & 0x7fffffff)
-	return masterKey.DeriveChild([]int{12381, coinType, 0, 0})
+	return masterKey.DeriveChild([]int{12381, CoinType, 0, 0})
 }
 
 func GetFarmerPrivateKeyByMnemonic(minerMnemonic string) (string, string, error) {
@@ -0,0 +1,182 @@
+package export
+
+import (
+	"chia-miner/pkg/bech32"
+	"chia-miner/pkg/bls"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+
+	bip39 "github.com/tyler-smith/go-bip39"
+)
+
+// CoinType is the hd path coin type of the keys
+const CoinType = 0x20fc
+
+// DefaultAddressPrefix bech32m prefix of wallet addresses
+const DefaultAddressPrefix = "qit"
+
+// p2DelegatedPuzzleOrHiddenPuzzleHash tree hash of the standard transaction puzzle
+var p2DelegatedPuzzleOrHiddenPuzzleHash, _ = hex.DecodeString("e9aaa49f45bad5c889b86ee3341550c155cfdd10c3a6757de618d20612fffd52")
+
+// defaultHiddenPuzzleHash tree hash of the default hidden puzzle (=)
+var defaultHiddenPuzzleHash, _ = hex.DecodeString("711d6c4e32c92e53179b199484cf8c897542bc57f2b22582799f9d657eec4699")
+
+// groupOrder order of the bls12-381 groups
+var groupOrder, _ = new(big.Int).SetString("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+
+// KeyPair hex encoded bls key pair
+type KeyPair struct {
+	PublicKey  string `json:"publicKey"`
+	PrivateKey string `json:"privateKey"`
+}
+
+// WalletKey wallet key pair and address at an index
+type WalletKey struct {
+	Index      int    `json:"index"`
+	PublicKey  string `json:"publicKey"`
+	PrivateKey string `json:"privateKey"`
+	PuzzleHash string `json:"puzzleHash"`
+	Address    string `json:"address"`
+	// ObserverAddress address of the unhardened wallet key at the same index
+	ObserverAddress string `json:"observerAddress"`
+}
+
+// Keys keys derived from a mnemonic
+type Keys struct {
+	Fingerprint     uint32      `json:"fingerprint"`
+	MasterPublicKey string      `json:"masterPublicKey"`
+	Farmer          KeyPair     `json:"farmer"`
+	Pool            KeyPair     `json:"pool"`
+	Local           KeyPair     `json:"local"`
+	Wallets         []WalletKey `json:"wallets"`
+}
+
+func CreatePoolKey(masterKey *bls.PrivateKey) *bls.PrivateKey {
+	return masterKey.DeriveChild([]int{12381, CoinType, 1, 0})
+}
+
+func CreateWalletKey(masterKey *bls.PrivateKey, index int) *bls.PrivateKey {
+	return masterKey.DeriveChild([]int{12381, CoinType, 2, index})
+}
+
+// CreateLocalKey derives the local master key, plot local keys are generated by the plotter
+func CreateLocalKey(masterKey *bls.PrivateKey) *bls.PrivateKey {
+	return masterKey.DeriveChild([]int{12381, CoinType, 3, 0})
+}
+
+// CreateObserverWalletKey derives the wallet key without hardening, the public key can be derived from the master public key
+func CreateObserverWalletKey(masterKey *bls.PrivateKey, index int) (*bls.PrivateKey, error) {
+	return masterKey.DeriveChildUnhardened([]int{12381, CoinType, 2, index})
+}
+
+// GetMasterKeyByMnemonic returns the master private key of a mnemonic
+func GetMasterKeyByMnemonic(mnemonic string) (*bls.PrivateKey, error) {
+	return bls.GenerateKeyFromSeed(bip39.NewSeed(mnemonic, ""))
+}
+
+func keyPair(key *bls.PrivateKey) KeyPair {
+	privateKey, _ := key.MarshalBinary()
+	publicKey, _ := key.Public().(*bls.PublicKey).MarshalBinary()
+	return KeyPair{PublicKey: hex.EncodeToString(publicKey), PrivateKey: hex.EncodeToString(privateKey)}
+}
+
+// ExportKeys derives the farmer, pool, local and the first wallets wallet keys of a mnemonic
+func ExportKeys(mnemonic string, wallets int, prefix string) (*Keys, error) {
+	masterKey, err := GetMasterKeyByMnemonic(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+	masterPk := masterKey.Public().(*bls.PublicKey)
+	masterPkBytes, _ := masterPk.MarshalBinary()
+	farmerKey := CreateFarmerKey(masterKey)
+	keys := &Keys{
+		Fingerprint:     masterPk.GetFingerprint(),
+		MasterPublicKey: hex.EncodeToString(masterPkBytes),
+		Farmer:          keyPair(farmerKey),
+		Pool:            keyPair(CreatePoolKey(masterKey)),
+		Local:           keyPair(CreateLocalKey(masterKey)),
+	}
+	for i := 0; i < wallets; i++ {
+		walletKey := CreateWalletKey(masterKey, i)
+		pair := keyPair(walletKey)
+		puzzleHash, err := PuzzleHashForPublicKey(walletKey.Public().(*bls.PublicKey))
+		if err != nil {
+			return nil, err
+		}
+		address, err := bech32.Encode(prefix, puzzleHash)
+		if err != nil {
+			return nil, err
+		}
+		observerKey, err := CreateObserverWalletKey(masterKey, i)
+		if err != nil {
+			return nil, err
+		}
+		observerPuzzleHash, err := PuzzleHashForPublicKey(observerKey.Public().(*bls.PublicKey))
+		if err != nil {
+			return nil, err
+		}
+		observerAddress, err := bech32.Encode(prefix, observerPuzzleHash)
+		if err != nil {
+			return nil, err
+		}
+		keys.Wallets = append(keys.Wallets, WalletKey{
+			Index:           i,
+			PublicKey:       pair.PublicKey,
+			PrivateKey:      pair.PrivateKey,
+			PuzzleHash:      hex.EncodeToString(puzzleHash),
+			Address:         address,
+			ObserverAddress: observerAddress,
+		})
+	}
+	return keys, nil
+}
+
+// syntheticPublicKey returns pk + g1 * (sha256(pk || hidden puzzle hash) mod r), the hash read as a signed integer
+func syntheticPublicKey(pk *bls.PublicKey) (*bls.PublicKey, error) {
+	pkBytes, _ := pk.MarshalBinary()
+	digest := sha256.Sum256(append(append([]byte{}, pkBytes...), defaultHiddenPuzzleHash...))
+	offset := new(big.Int).SetBytes(digest[:])
+	if digest[0]&0x80 != 0 {
+		offset.Sub(offset, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+	offset.Mod(offset, groupOrder)
+	offsetKey, err := bls.PrivateKeyFromBytes(offset.FillBytes(make([]byte, 32)))
+	if err != nil {
+		return nil, err
+	}
+	return pk.Add(offsetKey.Public().(*bls.PublicKey)), nil
+}
+
+func hashAtom(atom []byte) []byte {
+	digest := sha256.Sum256(append([]byte{1}, atom...))
+	return digest[:]
+}
+
+func hashPair(left, right []byte) []byte {
+	buf := make([]byte, 0, 65)
+	buf = append(buf, 2)
+	buf = append(buf, left...)
+	buf = append(buf, right...)
+	digest := sha256.Sum256(buf)
+	return digest[:]
+}
+
+// PuzzleHashForPublicKey returns the standard transaction puzzle hash of a wallet public key,
+// the tree hash of (a (q . mod) (c (q . synthetic_pk) 1))
+func PuzzleHashForPublicKey(pk *bls.PublicKey) ([]byte, error) {
+	synthetic, err := syntheticPublicKey(pk)
+	if err != nil {
+		return nil, err
+	}
+	syntheticBytes, _ := synthetic.MarshalBinary()
+	nilHash := hashAtom(nil)
+	// q and the environment are both the atom 1
+	opQ, opA, opC := hashAtom([]byte{1}), hashAtom([]byte{2}), hashAtom([]byte{4})
+	env := opQ
+
+	quotedArg := hashPair(opQ, hashAtom(syntheticBytes))
+	args := hashPair(opC, hashPair(quotedArg, hashPair(env, nilHash)))
+	quotedMod := hashPair(opQ, p2DelegatedPuzzleOrHiddenPuzzleHash)
+	return hashPair(opA, hashPair(quotedMod, hashPair(args, nilHash))), nil
+}
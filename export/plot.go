@@ -0,0 +1,45 @@
+package export
+
+import (
+	"chia-miner/pkg/bls"
+	"crypto/sha256"
+)
+
+// GetLocalPublicKey returns the plot local public key derived from the local master secret in the plot memo
+func GetLocalPublicKey(localMasterSecret []byte) (*bls.PublicKey, error) {
+	localMasterKey, err := bls.PrivateKeyFromBytes(localMasterSecret)
+	if err != nil {
+		return nil, err
+	}
+	return CreateLocalKey(localMasterKey).Public().(*bls.PublicKey), nil
+}
+
+// GetPlotPublicKey returns local_pk + farmer_pk, plus the taproot key for pool contract plots
+func GetPlotPublicKey(localPk, farmerPk *bls.PublicKey, includeTaproot bool) (*bls.PublicKey, error) {
+	plotPk := localPk.Add(farmerPk)
+	if !includeTaproot {
+		return plotPk, nil
+	}
+	sumBytes, _ := plotPk.MarshalBinary()
+	localBytes, _ := localPk.MarshalBinary()
+	farmerBytes, _ := farmerPk.MarshalBinary()
+	message := make([]byte, 0, len(sumBytes)+len(localBytes)+len(farmerBytes))
+	message = append(message, sumBytes...)
+	message = append(message, localBytes...)
+	message = append(message, farmerBytes...)
+	taprootHash := sha256.Sum256(message)
+	taprootKey, err := bls.GenerateKeyFromSeed(taprootHash[:])
+	if err != nil {
+		return nil, err
+	}
+	return plotPk.Add(taprootKey.Public().(*bls.PublicKey)), nil
+}
+
+// GetPlotId returns sha256(pool public key or pool contract puzzle hash || plot public key)
+func GetPlotId(pool []byte, plotPk *bls.PublicKey) []byte {
+	plotPkBytes, _ := plotPk.MarshalBinary()
+	hash := sha256.New()
+	hash.Write(pool)
+	hash.Write(plotPkBytes)
+	return hash.Sum(nil)
+}
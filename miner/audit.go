@@ -0,0 +1,126 @@
+package miner
+
+import (
+	chiapos2 "chia-miner/pkg/chiapos"
+	"chia-miner/pkg/remote"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"sort"
+)
+
+// ErrMissingFarmerKey plots whose farmer private key is not configured
+var ErrMissingFarmerKey = errors.New("farmer private key is not configured")
+
+// KeyStatus plots and capacity of a farmer and pool key pair, Harvester is
+// empty for the local plots
+type KeyStatus struct {
+	FarmerPublicKey string `json:"farmer_public_key"`
+	PoolPublicKey   string `json:"pool_public_key"`
+	Harvester       string `json:"harvester,omitempty"`
+	Plots           int    `json:"plots"`
+	Excluded        int    `json:"excluded"`
+	Capacity        uint64 `json:"capacity"`
+	Configured      bool   `json:"configured"`
+}
+
+// plotKeys groups the loaded plots of spaces by farmer and pool public key,
+// the harvester reports them to the farmer
+func plotKeys(spaces []*Space) []*remote.PlotKeys {
+	keys := make(map[string]*remote.PlotKeys)
+	list := make([]*remote.PlotKeys, 0)
+	for _, space := range spaces {
+		for _, f := range space.getFiles() {
+			fPubKey, _ := f.GetFarmerPublicKey()
+			poolKey, _ := f.GetPoolPublicKey()
+			k, ok := keys[fPubKey+poolKey]
+			if !ok {
+				k = &remote.PlotKeys{FarmerPublicKey: fPubKey, PoolPublicKey: poolKey}
+				keys[fPubKey+poolKey] = k
+				list = append(list, k)
+			}
+			k.Plots++
+			k.Capacity += chiapos2.PlotFileSize(f.GetSize())
+		}
+	}
+	return list
+}
+
+// AuditKeys groups all loaded plots, of the remote harvesters included, by
+// farmer and pool public key
+func (m *Miner) AuditKeys() []*KeyStatus {
+	keys := make(map[string]*KeyStatus)
+	get := func(fPubKey, poolKey, harvester string) *KeyStatus {
+		status, ok := keys[harvester+fPubKey+poolKey]
+		if !ok {
+			_, configured := m.config.FarmerKey[fPubKey]
+			status = &KeyStatus{
+				FarmerPublicKey: fPubKey,
+				PoolPublicKey:   poolKey,
+				Harvester:       harvester,
+				Configured:      configured,
+			}
+			keys[harvester+fPubKey+poolKey] = status
+		}
+		return status
+	}
+	add := func(f *plot, excluded bool) {
+		fPubKey, _ := f.GetFarmerPublicKey()
+		poolKey, _ := f.GetPoolPublicKey()
+		status := get(fPubKey, poolKey, "")
+		status.Plots++
+		status.Capacity += chiapos2.PlotFileSize(f.GetSize())
+		if excluded {
+			status.Excluded++
+		}
+	}
+	for _, space := range m.spaces {
+		for _, f := range space.getFiles() {
+			add(f, false)
+		}
+		for _, f := range space.getExcluded() {
+			add(f, true)
+		}
+	}
+	// the qualities of the harvester plots without a farmer key are dropped when excluded
+	for _, h := range m.farmer.keys() {
+		for _, k := range h.keys {
+			status := get(k.FarmerPublicKey, k.PoolPublicKey, h.name)
+			status.Plots += k.Plots
+			status.Capacity += k.Capacity
+			if !status.Configured && m.config.ExcludeMissingFarmerKey() {
+				status.Excluded += k.Plots
+			}
+		}
+	}
+
+	list := make([]*KeyStatus, 0, len(keys))
+	for _, status := range keys {
+		list = append(list, status)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Harvester != list[j].Harvester {
+			return list[i].Harvester < list[j].Harvester
+		}
+		if list[i].FarmerPublicKey != list[j].FarmerPublicKey {
+			return list[i].FarmerPublicKey < list[j].FarmerPublicKey
+		}
+		return list[i].PoolPublicKey < list[j].PoolPublicKey
+	})
+	return list
+}
+
+// logKeyAudit logs the plots per key, returns the number of plots whose farmer key is missing
+func (m *Miner) logKeyAudit() int {
+	missing := 0
+	for _, status := range m.AuditKeys() {
+		if status.Configured {
+			logrus.Infof("Farmer key %v pool key %v: %v plots, %.3f TiB",
+				status.FarmerPublicKey, status.PoolPublicKey, status.Plots, float64(status.Capacity)/(1<<40))
+			continue
+		}
+		missing += status.Plots
+		logrus.Errorf("Farmer key %v pool key %v: %v plots, %.3f TiB, farmer private key is not configured, %v plots excluded",
+			status.FarmerPublicKey, status.PoolPublicKey, status.Plots, float64(status.Capacity)/(1<<40), status.Excluded)
+	}
+	return missing
+}
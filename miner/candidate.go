@@ -0,0 +1,114 @@
+package miner
+
+import (
+	entity2 "chia-miner/miner/entity"
+	"chia-miner/pkg/bls"
+	chiapos2 "chia-miner/pkg/chiapos"
+	"chia-miner/pkg/history"
+	"context"
+	"encoding/hex"
+	"github.com/sirupsen/logrus"
+)
+
+// candidate a quality of a plot passing the filter, found in a local plot or by a remote harvester
+type candidate struct {
+	plotId   []byte
+	plotFile string
+	size     uint32
+	// memo the public keys of the plot memo, the local master secret is
+	// not set for the plots of remote harvesters
+	memo           *chiapos2.Memo
+	localPublicKey []byte
+	plotPublicKey  []byte
+	index          int
+	quality        []byte
+	// fetchProof reads the full proof of the quality
+	fetchProof func(ctx context.Context) ([]byte, error)
+	// submitCtx bounds the submission retries, it is cancelled when the challenge is superseded
+	submitCtx context.Context
+}
+
+// candidateHandler decides on a candidate, returns whether its proof was fetched
+type candidateHandler func(ctx context.Context, miningInfo *entity2.MiningInfo, challengeBytes []byte, c *candidate) bool
+
+// handleCandidate computes the deadline of a candidate, and fetches, signs and
+// submits the proof if it is within the target deadline, to the node with the
+// chain difficulty or as a partial to the pool with the pool difficulty
+func (m *Miner) handleCandidate(ctx context.Context, miningInfo *entity2.MiningInfo, challengeBytes []byte, c *candidate) bool {
+	requiredIters := chiapos2.CalculateIterationsQuality(c.quality, int32(c.size), miningInfo.Difficulty, challengeBytes)
+	inflate := 80 * 512 / (1 << miningInfo.FilterBits)
+	SubDeadline := (requiredIters * uint64(inflate)) / 24433591728
+	solo := int64(SubDeadline) < targetDeadline
+	poolDifficulty := m.pool.difficultyFor(c.memo)
+	partial := false
+	if poolDifficulty > 0 {
+		poolIters := chiapos2.CalculateIterationsQuality(c.quality, int32(c.size), poolDifficulty, challengeBytes)
+		partial = int64((poolIters*uint64(inflate))/24433591728) < targetDeadline
+	}
+	m.history.AddCandidate(&history.Candidate{
+		Height:         miningInfo.Height,
+		Challenge:      hex.EncodeToString(miningInfo.Challenge),
+		ScanIterations: miningInfo.ScanIterations,
+		PlotId:         hex.EncodeToString(c.plotId),
+		PlotFile:       c.plotFile,
+		ResponseNumber: int32(c.index),
+		RequiredIters:  requiredIters,
+		Deadline:       SubDeadline,
+	})
+	if !solo && !partial || ctx.Err() != nil {
+		return false
+	}
+
+	proof, err := c.fetchProof(ctx)
+	if err != nil {
+		logrus.Errorf("Failed to read proof %v %v", c.plotFile, err)
+		return false
+	}
+	fPubKey := hex.EncodeToString(c.memo.FarmerPublicKey)
+	privateKeyHex, ok := m.config.FarmerKey[fPubKey]
+	if !ok {
+		logrus.Errorf("Chia farmer private key is not configured, farmer public key %v", fPubKey)
+		return true
+	}
+	privateKey, err := bls.PrivateKeyFromHex(privateKeyHex)
+	if err != nil {
+		logrus.Errorf("Wrong farmer private key, farmer public key %v error %v", fPubKey, err)
+		return true
+	}
+
+	solo = solo && updateBestQuality(miningInfo, requiredIters)
+	if !solo && !partial {
+		return true
+	}
+	submitProof := &entity2.SubmitProof{
+		//Quality:         requiredIters,
+		Height:          miningInfo.Height,
+		ScanIterations:  miningInfo.ScanIterations,
+		Challenge:       hex.EncodeToString(miningInfo.Challenge),
+		QualityString:   hex.EncodeToString(c.quality),
+		PlotSize:        c.size,
+		PlotId:          hex.EncodeToString(c.plotId),
+		PoolKind:        c.memo.PoolKind(),
+		FarmerPublicKey: fPubKey,
+		PlotPublicKey:   hex.EncodeToString(c.plotPublicKey),
+		ResponseNumber:  int32(c.index),
+		ProofXs:         hex.EncodeToString(proof),
+		RequiredIters:   requiredIters,
+	}
+	if c.memo.IsPoolContract() {
+		submitProof.PoolContractPuzzleHash = hex.EncodeToString(c.memo.PoolContractPuzzleHash)
+	} else {
+		submitProof.PoolPublicKey = hex.EncodeToString(c.memo.PoolPublicKey)
+	}
+	if err := submitProof.Sign(privateKey); err != nil {
+		logrus.Errorf("Failed to sign proof, farmer public key %v error %v", fPubKey, err)
+		return true
+	}
+	if partial {
+		m.pool.submitPartial(submitProof, privateKey, poolDifficulty)
+	}
+	if solo {
+		m.submitter.submit(c.submitCtx, submitProof)
+	}
+	return true
+}
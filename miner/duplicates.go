@@ -0,0 +1,99 @@
+package miner
+
+import (
+	"chia-miner/pkg/config"
+	"encoding/hex"
+	"github.com/sirupsen/logrus"
+	"sync"
+)
+
+// DuplicatePlot a copy of a plot that is farmed from another file
+type DuplicatePlot struct {
+	File       string `json:"file"`
+	Id         string `json:"id"`
+	FarmedFile string `json:"farmed_file"`
+}
+
+// plotIndex indexes the loaded plots of all spaces by id, so a plot copied
+// into more than one plot directory is farmed once. It is shared by all spaces
+// like the scheduler.
+type plotIndex struct {
+	cfg      *config.Config
+	mutex    sync.Mutex
+	spaces   []*Space
+	reported map[string]bool // duplicate files already logged
+}
+
+func newPlotIndex(cfg *config.Config) *plotIndex {
+	return &plotIndex{cfg: cfg, reported: make(map[string]bool)}
+}
+
+func (x *plotIndex) add(s *Space) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.spaces = append(x.spaces, s)
+}
+
+// PreferredPlotFile reports whether the copy of a plot in file a is farmed
+// rather than the copy in file b, ordered by path priority then file name
+func PreferredPlotFile(cfg *config.Config, a, b string) bool {
+	pa, pb := cfg.GetPathPriority(a), cfg.GetPathPriority(b)
+	if pa != pb {
+		return pa < pb
+	}
+	return a < b
+}
+
+// resolve picks the copy of every plot id to farm and updates the farmed plots
+// and the duplicates of every space, it is called after a space is reloaded
+func (x *plotIndex) resolve() {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	best := make(map[string]*plot)
+	for _, s := range x.spaces {
+		for _, f := range s.getLoaded() {
+			id := string(f.GetId())
+			if current, ok := best[id]; !ok || PreferredPlotFile(x.cfg, f.GetFilename(), current.GetFilename()) {
+				best[id] = f
+			}
+		}
+	}
+
+	reported := make(map[string]bool)
+	changed := false
+	for _, s := range x.spaces {
+		loaded := s.getLoaded()
+		files := make([]*plot, 0, len(loaded))
+		duplicates := make([]*DuplicatePlot, 0)
+		for _, f := range loaded {
+			farmed := best[string(f.GetId())]
+			if farmed == f {
+				files = append(files, f)
+				continue
+			}
+			duplicate := &DuplicatePlot{
+				File:       f.GetFilename(),
+				Id:         hex.EncodeToString(f.GetId()),
+				FarmedFile: farmed.GetFilename(),
+			}
+			duplicates = append(duplicates, duplicate)
+			reported[duplicate.File] = true
+			if !x.reported[duplicate.File] {
+				changed = true
+				logrus.Warnf("Duplicate plot %v, id %v, not farmed, farming %v", duplicate.File, duplicate.Id, duplicate.FarmedFile)
+			}
+		}
+		s.files.Store(files)
+		s.duplicates.Store(duplicates)
+	}
+	for file := range x.reported {
+		if !reported[file] {
+			changed = true
+		}
+	}
+	if changed {
+		logrus.Infof("%v duplicate plots are not farmed", len(reported))
+	}
+	x.reported = reported
+}
@@ -0,0 +1,157 @@
+package miner
+
+import (
+	"chia-miner/pkg/config"
+	"chia-miner/pkg/metrics"
+	"sync"
+	"time"
+)
+
+// EndpointStatus health of a node rpc endpoint
+type EndpointStatus struct {
+	Url         string `json:"url"`
+	Priority    int    `json:"priority"`
+	Active      bool   `json:"active"`
+	Healthy     bool   `json:"healthy"`
+	Stale       bool   `json:"stale"`
+	Height      uint32 `json:"height"`
+	Failures    int    `json:"failures"` // consecutive failed requests
+	LastError   string `json:"last_error,omitempty"`
+	LastSuccess int64  `json:"last_success"`
+}
+
+// endpoint a node and its health, an endpoint is healthy until a request fails
+type endpoint struct {
+	config.RpcEndpoint
+	mutex  sync.Mutex
+	status EndpointStatus
+}
+
+func newEndpoint(cfg config.RpcEndpoint) *endpoint {
+	return &endpoint{
+		RpcEndpoint: cfg,
+		status: EndpointStatus{
+			Url:      cfg.Url,
+			Priority: cfg.Priority,
+			Healthy:  true,
+		},
+	}
+}
+
+func (e *endpoint) succeed(height uint32) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.status.Healthy = true
+	e.status.Height = height
+	e.status.Failures = 0
+	e.status.LastError = ""
+	e.status.LastSuccess = time.Now().Unix()
+}
+
+func (e *endpoint) fail(err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.status.Healthy = false
+	e.status.Failures++
+	e.status.LastError = err.Error()
+}
+
+// usable reports whether the endpoint is healthy and not behind the other nodes
+func (e *endpoint) usable() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.status.Healthy && !e.status.Stale
+}
+
+func (e *endpoint) getStatus() EndpointStatus {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.status
+}
+
+// updateHealth marks the endpoints more than staleHeight blocks behind the
+// best healthy endpoint as stale
+func (j *JsonRpc) updateHealth() {
+	var best uint32
+	for _, e := range j.endpoints {
+		if status := e.getStatus(); status.Healthy && status.Height > best {
+			best = status.Height
+		}
+	}
+	for _, e := range j.endpoints {
+		e.mutex.Lock()
+		e.status.Stale = e.status.Healthy && e.status.Height+j.staleHeight < best
+		up := 0.0
+		if e.status.Healthy && !e.status.Stale {
+			up = 1
+		}
+		e.mutex.Unlock()
+		metrics.RpcEndpointUp.WithLabelValues(e.Url).Set(up)
+	}
+}
+
+// candidates returns the endpoints to try in order: the usable ones by
+// priority, then the stale and the failed ones as a last resort
+func (j *JsonRpc) candidates() []*endpoint {
+	list := make([]*endpoint, 0, len(j.endpoints))
+	var rest []*endpoint
+	for _, e := range j.endpoints {
+		if e.usable() {
+			list = append(list, e)
+		} else {
+			rest = append(rest, e)
+		}
+	}
+	return append(list, rest...)
+}
+
+// healthy returns the usable endpoints, or every endpoint if none is usable
+func (j *JsonRpc) healthy() []*endpoint {
+	var list []*endpoint
+	for _, e := range j.endpoints {
+		if e.usable() {
+			list = append(list, e)
+		}
+	}
+	if len(list) == 0 {
+		return j.endpoints
+	}
+	return list
+}
+
+// checkEndpoints polls the endpoints not in use, so a recovered node is used again
+// and the height of the active node can be compared
+func (j *JsonRpc) checkEndpoints() {
+	active := j.getActive()
+	var wg sync.WaitGroup
+	for _, e := range j.endpoints {
+		if e == active {
+			continue
+		}
+		wg.Add(1)
+		go func(e *endpoint) {
+			defer wg.Done()
+			j.fetchMiningInfo(e)
+		}(e)
+	}
+	wg.Wait()
+	j.updateHealth()
+}
+
+func (j *JsonRpc) getActive() *endpoint {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.active
+}
+
+// GetEndpoints returns the health of the configured nodes
+func (j *JsonRpc) GetEndpoints() []EndpointStatus {
+	active := j.getActive()
+	list := make([]EndpointStatus, 0, len(j.endpoints))
+	for _, e := range j.endpoints {
+		status := e.getStatus()
+		status.Active = e == active
+		list = append(list, status)
+	}
+	return list
+}
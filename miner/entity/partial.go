@@ -0,0 +1,93 @@
+package entity
+
+import (
+	"chia-miner/pkg/bls"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+)
+
+// PostPartial a partial proof submitted to the pool, the proof is signed by
+// the farmer key and the partial by the authentication key
+type PostPartial struct {
+	LauncherId          string        `json:"launcher_id"`
+	AuthenticationToken uint64        `json:"authentication_token"`
+	Proof               *PartialProof `json:"proof"`
+	Signature           string        `json:"signature"`
+}
+
+// PartialProof the proof sent to the pool, the fields of SubmitProof the farmer
+// signature covers plus the plot and farmer public keys it is verified with
+type PartialProof struct {
+	Challenge              string `json:"challenge"`
+	Height                 uint32 `json:"height"`
+	ScanIterations         int64  `json:"scan_iterations"`
+	QualityString          string `json:"quality_string"`
+	PlotSize               uint32 `json:"plot_size"`
+	PlotId                 string `json:"plot_id"`
+	PlotPublicKey          string `json:"plot_public_key"`
+	PoolContractPuzzleHash string `json:"pool_contract_puzzle_hash"`
+	FarmerPublicKey        string `json:"farmer_public_key"`
+	ResponseNumber         int32  `json:"response_number"`
+	ProofXs                string `json:"proof_xs"`
+	Signature              string `json:"signature"`
+}
+
+// NewPartialProof returns the partial of a signed proof of a pool contract plot
+func NewPartialProof(proof *SubmitProof) *PartialProof {
+	return &PartialProof{
+		Challenge:              proof.Challenge,
+		Height:                 proof.Height,
+		ScanIterations:         proof.ScanIterations,
+		QualityString:          proof.QualityString,
+		PlotSize:               proof.PlotSize,
+		PlotId:                 proof.PlotId,
+		PlotPublicKey:          proof.PlotPublicKey,
+		PoolContractPuzzleHash: proof.PoolContractPuzzleHash,
+		FarmerPublicKey:        proof.FarmerPublicKey,
+		ResponseNumber:         proof.ResponseNumber,
+		ProofXs:                proof.ProofXs,
+		Signature:              proof.Signature,
+	}
+}
+
+// AuthenticationMessage returns the message signed by the authentication key:
+// sha256(method || launcher_id || authentication_token || payload)
+func AuthenticationMessage(method string, launcherId []byte, token uint64, payload []byte) []byte {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write(launcherId)
+	var data [8]byte
+	binary.BigEndian.PutUint64(data[:], token)
+	hash.Write(data[:])
+	hash.Write(payload)
+	return hash.Sum(nil)
+}
+
+// SignatureMessage returns the message signed by the authentication key, the
+// payload is the signature of the proof
+func (p *PostPartial) SignatureMessage() ([]byte, error) {
+	launcherId, err := hex.DecodeString(p.LauncherId)
+	if err != nil {
+		return nil, err
+	}
+	proofSignature, err := hex.DecodeString(p.Proof.Signature)
+	if err != nil {
+		return nil, err
+	}
+	return AuthenticationMessage("post_partial", launcherId, p.AuthenticationToken, proofSignature), nil
+}
+
+// Sign signs the partial with the authentication key
+func (p *PostPartial) Sign(privateKey *bls.PrivateKey) error {
+	message, err := p.SignatureMessage()
+	if err != nil {
+		return err
+	}
+	signature, err := privateKey.SignMessage(message)
+	if err != nil {
+		return err
+	}
+	p.Signature = hex.EncodeToString(signature)
+	return nil
+}
@@ -0,0 +1,7 @@
+package entity
+
+// SubmitResult result of pos_submitSignedProof
+type SubmitResult struct {
+	Accepted bool   `json:"accepted"`
+	Message  string `json:"message,omitempty"`
+}
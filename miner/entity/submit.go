@@ -1,26 +1,93 @@
 package entity
 
-import "encoding/json"
+import (
+	"chia-miner/pkg/bls"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+)
 
 type SubmitProof struct {
 	//Quality         uint64
-	QualityString    string `json:"quality_string"`
-	PlotSize         uint32 `json:"plot_size"`
-	PlotId           string `json:"plot_id"`
-	PoolPublicKey    string `json:"pool_public_key"`
-	FarmerPublicKey  string `json:"farmer_public_key"`
-	FarmerPrivateKey string `json:"farmer_private_key"`
-	SecurityKey      string `json:"security_key"`
-	ResponseNumber   int32  `json:"response_number"`
-	ProofXs          string `json:"proof_xs"`
-	Challenge        string `json:"challenge"`
-	SpHash           string `json:"sp_hash"`
-	RequiredIters    uint64 `json:"required_iters"`
-	Height           uint32 `json:"height"`
-	ScanIterations   int64  `json:"scan_iterations"`
+	QualityString string `json:"quality_string"`
+	PlotSize      uint32 `json:"plot_size"`
+	PlotId        string `json:"plot_id"`
+	// PoolKind tells which of PoolPublicKey and PoolContractPuzzleHash is set
+	PoolKind               string `json:"pool_kind"`
+	PoolPublicKey          string `json:"pool_public_key,omitempty"`
+	PoolContractPuzzleHash string `json:"pool_contract_puzzle_hash,omitempty"`
+	FarmerPublicKey        string `json:"farmer_public_key"`
+	PlotPublicKey          string `json:"plot_public_key"` // local public key + farmer public key
+	ResponseNumber         int32  `json:"response_number"`
+	ProofXs                string `json:"proof_xs"`
+	Challenge              string `json:"challenge"`
+	SpHash                 string `json:"sp_hash"`
+	RequiredIters          uint64 `json:"required_iters"`
+	Height                 uint32 `json:"height"`
+	ScanIterations         int64  `json:"scan_iterations"`
+	Signature              string `json:"signature"`
 }
 
 func (s *SubmitProof) ToString() string {
 	data, _ := json.Marshal(s)
 	return string(data)
 }
+
+// SignatureMessage returns the message signed by the farmer key:
+// sha256(challenge || quality_string || proof_xs || plot_id || response_number || pool),
+// response_number is a big endian uint32, pool the pool public key or contract puzzle hash
+func (s *SubmitProof) SignatureMessage() ([]byte, error) {
+	pool := s.PoolPublicKey
+	if s.PoolContractPuzzleHash != "" {
+		pool = s.PoolContractPuzzleHash
+	}
+	hash := sha256.New()
+	for _, field := range []string{s.Challenge, s.QualityString, s.ProofXs, s.PlotId} {
+		data, err := hex.DecodeString(field)
+		if err != nil {
+			return nil, err
+		}
+		hash.Write(data)
+	}
+	var index [4]byte
+	binary.BigEndian.PutUint32(index[:], uint32(s.ResponseNumber))
+	hash.Write(index[:])
+	data, err := hex.DecodeString(pool)
+	if err != nil {
+		return nil, err
+	}
+	hash.Write(data)
+	return hash.Sum(nil), nil
+}
+
+// Sign signs the proof with the farmer private key
+func (s *SubmitProof) Sign(privateKey *bls.PrivateKey) error {
+	message, err := s.SignatureMessage()
+	if err != nil {
+		return err
+	}
+	signature, err := privateKey.SignMessage(message)
+	if err != nil {
+		return err
+	}
+	s.Signature = hex.EncodeToString(signature)
+	return nil
+}
+
+// VerifySignature verifies the signature against the farmer public key
+func (s *SubmitProof) VerifySignature() error {
+	publicKey, err := bls.PublicKeyFromHex(s.FarmerPublicKey)
+	if err != nil {
+		return err
+	}
+	signature, err := hex.DecodeString(s.Signature)
+	if err != nil {
+		return err
+	}
+	message, err := s.SignatureMessage()
+	if err != nil {
+		return err
+	}
+	return publicKey.Verify(message, signature)
+}
@@ -13,6 +13,14 @@ var (
 	ErrBadRequest       = status.Error(codes.InvalidArgument, "bad request")
 	ErrNotFoundData     = status.Error(codes.NotFound, "not found data")
 	ErrBadMiningInfo    = errors.New("bad mining info")
+	ErrStaleHeight      = errors.New("stale height")
+	ErrBadPoolConfig    = errors.New("bad pool config")
+	ErrReadProof        = errors.New("failed to read proof")
+	ErrInvalidProof     = errors.New("invalid proof, the plot may be corrupted")
+	ErrPlotNotFound     = status.Error(codes.NotFound, "plot not found")
+	ErrHarvesterClosed  = errors.New("harvester disconnected")
+	ErrBadPlotKeys      = errors.New("plot keys do not derive the plot id")
+	ErrFarmerToken      = errors.New("farmer.token is required on a non loopback address")
 )
 
 // RawRpcError raw rpc error
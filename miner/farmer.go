@@ -0,0 +1,433 @@
+package miner
+
+import (
+	"bytes"
+	export "chia-miner/export"
+	"chia-miner/miner/entity"
+	"chia-miner/pkg/bls"
+	chiapos2 "chia-miner/pkg/chiapos"
+	"chia-miner/pkg/remote"
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	proofRequestTimeout = 30 * time.Second
+	harvesterQueueSize  = 256
+)
+
+// HarvesterStatus a connected remote harvester
+type HarvesterStatus struct {
+	Name      string `json:"name"`
+	Address   string `json:"address"`
+	Plots     int    `json:"plots"`
+	Capacity  uint64 `json:"capacity"`
+	RawSpace  uint64 `json:"raw_space"`
+	Connected int64  `json:"connected"`
+	Qualities int64  `json:"qualities"`
+}
+
+// remoteHarvester a harvester connected to the farmer
+type remoteHarvester struct {
+	status    HarvesterStatus
+	keys      []*remote.PlotKeys
+	qualities int64
+	out       chan *remote.FarmerMessage
+	closed    chan struct{}
+	mutex     sync.Mutex
+	pending   map[uint64]chan *remote.Proof
+}
+
+// send queues a message, it is dropped if the harvester does not keep up
+func (h *remoteHarvester) send(msg *remote.FarmerMessage) bool {
+	select {
+	case h.out <- msg:
+		return true
+	default:
+		logrus.Warnf("Harvester %v is not keeping up, message dropped", h.name())
+		return false
+	}
+}
+
+// requestProof asks the harvester for the full proof of a quality and waits for it
+func (h *remoteHarvester) requestProof(ctx context.Context, request *remote.ProofRequest) ([]byte, error) {
+	ch := make(chan *remote.Proof, 1)
+	h.mutex.Lock()
+	h.pending[request.Id] = ch
+	h.mutex.Unlock()
+	defer func() {
+		h.mutex.Lock()
+		delete(h.pending, request.Id)
+		h.mutex.Unlock()
+	}()
+
+	if !h.send(&remote.FarmerMessage{ProofRequest: request}) {
+		return nil, ErrReadProof
+	}
+	timer := time.NewTimer(proofRequestTimeout)
+	defer timer.Stop()
+	select {
+	case proof := <-ch:
+		if proof.Error != "" {
+			return nil, errors.New(proof.Error)
+		}
+		return proof.Proof, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-h.closed:
+		return nil, ErrHarvesterClosed
+	case <-timer.C:
+		return nil, errors.Wrapf(ErrReadProof, "no answer from harvester %v", h.name())
+	}
+}
+
+// deliver hands a proof to the waiting request
+func (h *remoteHarvester) deliver(proof *remote.Proof) {
+	h.mutex.Lock()
+	ch, ok := h.pending[proof.Id]
+	h.mutex.Unlock()
+	if !ok {
+		return
+	}
+	select {
+	case ch <- proof:
+	default:
+	}
+}
+
+func (h *remoteHarvester) name() string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.status.Name
+}
+
+// farmerServer accepts remote harvesters
+type farmerServer struct {
+	miner      *Miner
+	address    string // listen address, the port is chosen when the configured one is 0
+	token      string
+	handle     candidateHandler
+	requestId  uint64
+	mutex      sync.RWMutex
+	harvesters map[*remoteHarvester]struct{}
+}
+
+// startFarmer serves the harvester protocol on the configured address, a
+// token is required unless it is a loopback address
+func (m *Miner) startFarmer() error {
+	address := m.config.GetFarmerListen()
+	loopback := isLoopback(address)
+	if !loopback && m.config.Farmer.Token == "" {
+		return errors.Wrapf(ErrFarmerToken, "listen %v", address)
+	}
+	var options []grpc.ServerOption
+	if m.config.Farmer.CertFile != "" || m.config.Farmer.KeyFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(m.config.Farmer.CertFile, m.config.Farmer.KeyFile)
+		if err != nil {
+			return err
+		}
+		options = append(options, grpc.Creds(creds))
+	} else if !loopback {
+		logrus.Warnf("Farmer listens on %v without tls, the harvester token is sent in plaintext", address)
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	m.farmer = &farmerServer{
+		miner:      m,
+		address:    listener.Addr().String(),
+		token:      m.config.Farmer.Token,
+		handle:     m.handleCandidate,
+		harvesters: make(map[*remoteHarvester]struct{}),
+	}
+	server := grpc.NewServer(options...)
+	remote.RegisterFarmerServer(server, m.farmer)
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			logrus.Errorf("Farmer server stopped %v", err)
+		}
+	}()
+	logrus.Infof("Farmer listening for harvesters on %v", m.farmer.address)
+	return nil
+}
+
+// isLoopback reports whether a listen address only accepts local connections
+func isLoopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// challengeMessage converts mining info to the challenge sent to harvesters
+func challengeMessage(miningInfo *entity.MiningInfo) *remote.FarmerMessage {
+	return &remote.FarmerMessage{Challenge: &remote.Challenge{
+		Height:         miningInfo.Height,
+		Challenge:      miningInfo.Challenge,
+		Difficulty:     miningInfo.Difficulty,
+		FilterBits:     miningInfo.FilterBits,
+		ScanIterations: miningInfo.ScanIterations,
+	}}
+}
+
+// broadcastMessage sends a challenge to every harvester
+func (f *farmerServer) broadcastMessage(msg *remote.FarmerMessage) {
+	if f == nil {
+		return
+	}
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	for h := range f.harvesters {
+		h.send(msg)
+	}
+}
+
+// list returns the status of the connected harvesters
+func (f *farmerServer) list() []*HarvesterStatus {
+	list := make([]*HarvesterStatus, 0)
+	if f == nil {
+		return list
+	}
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	for h := range f.harvesters {
+		h.mutex.Lock()
+		status := h.status
+		h.mutex.Unlock()
+		status.Qualities = atomic.LoadInt64(&h.qualities)
+		list = append(list, &status)
+	}
+	return list
+}
+
+// harvesterKeys the plot keys reported by a harvester
+type harvesterKeys struct {
+	name string
+	keys []*remote.PlotKeys
+}
+
+// keys returns the plot keys of the connected harvesters
+func (f *farmerServer) keys() []harvesterKeys {
+	list := make([]harvesterKeys, 0)
+	if f == nil {
+		return list
+	}
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	for h := range f.harvesters {
+		h.mutex.Lock()
+		list = append(list, harvesterKeys{name: h.status.Name, keys: h.keys})
+		h.mutex.Unlock()
+	}
+	return list
+}
+
+// auditKeys logs the plots of a harvester whose farmer key is not configured,
+// returns the number of these plots
+func (f *farmerServer) auditKeys(name string, keys []*remote.PlotKeys) int {
+	missing := 0
+	for _, k := range keys {
+		if _, ok := f.miner.config.FarmerKey[k.FarmerPublicKey]; ok {
+			continue
+		}
+		missing += k.Plots
+		logrus.Errorf("Harvester %v farmer key %v pool key %v: %v plots, %.3f TiB, farmer private key is not configured",
+			name, k.FarmerPublicKey, k.PoolPublicKey, k.Plots, float64(k.Capacity)/(1<<40))
+	}
+	return missing
+}
+
+func (f *farmerServer) checkToken(ctx context.Context) error {
+	if f.token == "" {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, token := range md.Get(remote.TokenHeader) {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(f.token)) == 1 {
+			return nil
+		}
+	}
+	return ErrUnauthenticated
+}
+
+// Connect serves the stream of one harvester
+func (f *farmerServer) Connect(stream remote.FarmerConnectServer) error {
+	if err := f.checkToken(stream.Context()); err != nil {
+		return err
+	}
+	h := &remoteHarvester{
+		status:  HarvesterStatus{Connected: time.Now().Unix()},
+		out:     make(chan *remote.FarmerMessage, harvesterQueueSize),
+		closed:  make(chan struct{}),
+		pending: make(map[uint64]chan *remote.Proof),
+	}
+	if p, ok := peer.FromContext(stream.Context()); ok {
+		h.status.Address = p.Addr.String()
+	}
+	h.status.Name = h.status.Address
+
+	f.mutex.Lock()
+	f.harvesters[h] = struct{}{}
+	f.mutex.Unlock()
+	defer func() {
+		f.mutex.Lock()
+		delete(f.harvesters, h)
+		f.mutex.Unlock()
+		close(h.closed)
+		f.miner.updatePlotMetrics()
+	}()
+
+	// the writer owns the send side of the stream
+	go func() {
+		for {
+			select {
+			case msg := <-h.out:
+				if err := stream.Send(msg); err != nil {
+					return
+				}
+			case <-h.closed:
+				return
+			}
+		}
+	}()
+	if miningInfo := f.miner.GetMiningInfo(); miningInfo != nil {
+		h.send(challengeMessage(miningInfo))
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			logrus.Infof("Harvester %v disconnected %v", h.name(), err)
+			return err
+		}
+		switch {
+		case msg.Hello != nil:
+			h.mutex.Lock()
+			if msg.Hello.Name != "" {
+				h.status.Name = msg.Hello.Name
+			}
+			h.status.Plots = msg.Hello.Plots
+			h.status.Capacity = msg.Hello.Capacity
+			h.status.RawSpace = msg.Hello.RawSpace
+			h.keys = msg.Hello.Keys
+			h.mutex.Unlock()
+			logrus.Infof("Harvester %v at %v: %v plots, %.3f TiB", msg.Hello.Name, h.status.Address,
+				msg.Hello.Plots, float64(msg.Hello.Capacity)/(1<<40))
+			f.miner.updatePlotMetrics()
+			if missing := f.auditKeys(h.name(), msg.Hello.Keys); missing > 0 && f.miner.config.MissingFarmerKey == "refuse" {
+				logrus.Errorf("Harvester %v refused, %v plots without a configured farmer key", h.name(), missing)
+				return status.Errorf(codes.FailedPrecondition, "%v: %v plots", ErrMissingFarmerKey, missing)
+			}
+		case msg.Quality != nil:
+			atomic.AddInt64(&h.qualities, 1)
+			go f.handleQuality(h, msg.Quality)
+		case msg.Proof != nil:
+			h.deliver(msg.Proof)
+		}
+	}
+}
+
+// handleQuality hands a quality found by a harvester to the candidate handler,
+// qualities of a superseded challenge are dropped
+func (f *farmerServer) handleQuality(h *remoteHarvester, quality *remote.Quality) {
+	m := f.miner
+	m.mutex.RLock()
+	miningInfo, ctx := m.miningInfo, m.scanCtx
+	m.mutex.RUnlock()
+	if miningInfo == nil || !bytes.Equal(miningInfo.Challenge, quality.Challenge) || miningInfo.ScanIterations != quality.ScanIterations {
+		logrus.Debugf("Drop obsolete quality of harvester %v height %v", h.name(), quality.Height)
+		return
+	}
+	memo := &chiapos2.Memo{
+		PoolPublicKey:          quality.PoolPublicKey,
+		PoolContractPuzzleHash: quality.PoolContractPuzzleHash,
+		FarmerPublicKey:        quality.FarmerPublicKey,
+	}
+	if err := checkPlotKeys(memo, quality); err != nil {
+		logrus.Warnf("Harvester %v sent plot %v with %v", h.name(), quality.PlotFile, err)
+		return
+	}
+	if _, ok := m.config.FarmerKey[hex.EncodeToString(memo.FarmerPublicKey)]; !ok && m.config.ExcludeMissingFarmerKey() {
+		logrus.Debugf("Drop quality of harvester %v plot %v, %v", h.name(), quality.PlotFile, ErrMissingFarmerKey)
+		return
+	}
+
+	challengeBytes := plotChallenge(miningInfo.Challenge, miningInfo.ScanIterations)
+	c := &candidate{
+		plotId:         quality.PlotId,
+		plotFile:       h.name() + ":" + quality.PlotFile,
+		size:           quality.Size,
+		memo:           memo,
+		localPublicKey: quality.LocalPublicKey,
+		plotPublicKey:  quality.PlotPublicKey,
+		index:          quality.Index,
+		quality:        quality.Quality,
+		// the proof of a harvester is verified before the farmer signs it
+		fetchProof: func(ctx context.Context) ([]byte, error) {
+			proof, err := h.requestProof(ctx, &remote.ProofRequest{
+				Id:        atomic.AddUint64(&f.requestId, 1),
+				Challenge: challengeBytes,
+				PlotId:    quality.PlotId,
+				Index:     quality.Index,
+				Quality:   quality.Quality,
+			})
+			if err != nil {
+				return nil, err
+			}
+			verified, ok := chiapos2.VerifyProof(quality.PlotId, quality.Size, challengeBytes, proof)
+			if !ok || !bytes.Equal(verified, quality.Quality) {
+				logrus.Warnf("Harvester %v sent an invalid proof of %v", h.name(), quality.PlotFile)
+				return nil, ErrInvalidProof
+			}
+			return proof, nil
+		},
+		submitCtx: ctx,
+	}
+	f.handle(ctx, miningInfo, challengeBytes, c)
+}
+
+// checkPlotKeys checks the public keys reported by a harvester derive the plot
+// public key and the plot id, the harvester does not send the local master secret
+func checkPlotKeys(memo *chiapos2.Memo, quality *remote.Quality) error {
+	localPk, err := bls.PublicKeyFromBytes(quality.LocalPublicKey)
+	if err != nil {
+		return errors.Wrap(ErrBadPlotKeys, err.Error())
+	}
+	farmerPk, err := bls.PublicKeyFromBytes(memo.FarmerPublicKey)
+	if err != nil {
+		return errors.Wrap(ErrBadPlotKeys, err.Error())
+	}
+	plotPk, err := export.GetPlotPublicKey(localPk, farmerPk, memo.IsPoolContract())
+	if err != nil {
+		return errors.Wrap(ErrBadPlotKeys, err.Error())
+	}
+	plotPkBytes, _ := plotPk.MarshalBinary()
+	if !bytes.Equal(plotPkBytes, quality.PlotPublicKey) || !bytes.Equal(export.GetPlotId(memo.Pool(), plotPk), quality.PlotId) {
+		return ErrBadPlotKeys
+	}
+	return nil
+}
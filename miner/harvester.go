@@ -0,0 +1,270 @@
+package miner
+
+import (
+	"bytes"
+	entity2 "chia-miner/miner/entity"
+	"chia-miner/pkg/config"
+	"chia-miner/pkg/metrics"
+	"chia-miner/pkg/remote"
+	"chia-miner/utils"
+	"context"
+	"encoding/hex"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+const maxReconnectDelay = 30 * time.Second
+
+// Harvester scans the local plots for a remote farmer
+type Harvester struct {
+	config *config.Config
+	spaces []*Space
+
+	sendMutex sync.Mutex
+	stream    remote.FarmerConnectClient
+
+	mutex      sync.Mutex
+	miningInfo *entity2.MiningInfo
+	scanCtx    context.Context
+	cancelScan context.CancelFunc
+}
+
+func NewHarvester(config *config.Config) *Harvester {
+	return &Harvester{config: config}
+}
+
+// Start loads the plots and connects to the farmer
+func (h *Harvester) Start() error {
+	sched := newScheduler(h.config)
+	index := newPlotIndex(h.config)
+	for _, filepath := range h.config.Path {
+		space := NewSpace(filepath, h.config, sched, index)
+		space.handle = h.sendQuality
+		h.spaces = append(h.spaces, space)
+	}
+	h.updateMetrics()
+	go h.run()
+
+	reloadInterval := h.config.PlotReloadInterval
+	if reloadInterval == 0 {
+		reloadInterval = defaultPlotReloadInterval
+	}
+	if reloadInterval > 0 {
+		utils.StartTime(h.reloadPlots, reloadInterval*1000)
+	}
+	return nil
+}
+
+// reloadPlots rescans the plot directories and reports the new plot count to the farmer
+func (h *Harvester) reloadPlots() {
+	for _, space := range h.spaces {
+		space.reload()
+	}
+	h.updateMetrics()
+	if err := h.send(h.hello()); err != nil {
+		logrus.Debugf("Failed to report plots to the farmer %v", err)
+	}
+}
+
+// updateMetrics updates the plot gauges, a harvester has no chain gauges
+func (h *Harvester) updateMetrics() {
+	var plots int
+	var space uint64
+	for _, s := range h.spaces {
+		status := s.Status()
+		plots += status.Plots
+		space += status.RawSpace
+	}
+	metrics.Plots.Set(float64(plots))
+	metrics.SpaceBytes.Set(float64(space))
+}
+
+func (h *Harvester) hello() *remote.HarvesterMessage {
+	hello := &remote.Hello{Name: h.config.GetHarvesterName(), Keys: plotKeys(h.spaces)}
+	for _, space := range h.spaces {
+		status := space.Status()
+		hello.Plots += status.Plots
+		hello.Capacity += status.Capacity
+		hello.RawSpace += status.RawSpace
+	}
+	return &remote.HarvesterMessage{Hello: hello}
+}
+
+// send sends a message to the farmer, grpc streams do not support concurrent sends
+func (h *Harvester) send(msg *remote.HarvesterMessage) error {
+	h.sendMutex.Lock()
+	defer h.sendMutex.Unlock()
+	if h.stream == nil {
+		return ErrHarvesterClosed
+	}
+	return h.stream.Send(msg)
+}
+
+func (h *Harvester) setStream(stream remote.FarmerConnectClient) {
+	h.sendMutex.Lock()
+	h.stream = stream
+	h.sendMutex.Unlock()
+}
+
+// run keeps the connection to the farmer open
+func (h *Harvester) run() {
+	delay := time.Second
+	for {
+		start := time.Now()
+		err := h.connect()
+		if time.Since(start) > maxReconnectDelay {
+			delay = time.Second
+		}
+		logrus.Warnf("Disconnected from farmer %v %v, reconnecting in %v", h.config.Harvester.Farmer, err, delay)
+		time.Sleep(delay)
+		if delay *= 2; delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+func (h *Harvester) connect() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	creds := insecure.NewCredentials()
+	if h.config.Harvester.CaFile != "" {
+		tlsCreds, err := credentials.NewClientTLSFromFile(h.config.Harvester.CaFile, h.config.Harvester.ServerName)
+		if err != nil {
+			return err
+		}
+		creds = tlsCreds
+	}
+	conn, err := grpc.DialContext(ctx, h.config.Harvester.Farmer, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if h.config.Harvester.Token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, remote.TokenHeader, h.config.Harvester.Token)
+	}
+	stream, err := remote.Connect(ctx, conn)
+	if err != nil {
+		return err
+	}
+	h.setStream(stream)
+	defer h.setStream(nil)
+	if err := h.send(h.hello()); err != nil {
+		return err
+	}
+	logrus.Infof("Connected to farmer %v", h.config.Harvester.Farmer)
+
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		switch {
+		case msg.Challenge != nil:
+			h.onChallenge(msg.Challenge)
+		case msg.ProofRequest != nil:
+			go h.serveProof(msg.ProofRequest)
+		}
+	}
+}
+
+// onChallenge starts scanning a challenge, the scan of a superseded challenge is cancelled
+func (h *Harvester) onChallenge(challenge *remote.Challenge) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	miningInfo := &entity2.MiningInfo{
+		Height:         challenge.Height,
+		Challenge:      challenge.Challenge,
+		Difficulty:     challenge.Difficulty,
+		FilterBits:     challenge.FilterBits,
+		ScanIterations: challenge.ScanIterations,
+		ReceiveTime:    time.Now().Unix(),
+		BestQuality:    math.MaxInt64,
+	}
+	if h.miningInfo != nil && h.miningInfo.IsSame(miningInfo) && !challenge.Rescan {
+		return
+	}
+	if h.miningInfo == nil || !bytes.Equal(h.miningInfo.Challenge, miningInfo.Challenge) {
+		if h.cancelScan != nil {
+			h.cancelScan()
+		}
+		h.scanCtx, h.cancelScan = context.WithCancel(context.Background())
+	}
+	h.miningInfo = miningInfo
+	for _, space := range h.spaces {
+		space.requestScan(h.scanCtx, miningInfo)
+	}
+	logrus.Infof("new block: height%v challenge[%v] scanIterations[%v]",
+		miningInfo.Height, hex.EncodeToString(miningInfo.Challenge), miningInfo.ScanIterations)
+}
+
+// qualityMessage converts a candidate to the quality sent to the farmer
+func qualityMessage(miningInfo *entity2.MiningInfo, c *candidate) *remote.Quality {
+	return &remote.Quality{
+		Height:         miningInfo.Height,
+		Challenge:      miningInfo.Challenge,
+		ScanIterations: miningInfo.ScanIterations,
+		PlotId:         c.plotId,
+		PlotFile:       c.plotFile,
+		Size:           c.size,
+		// public keys only, the memo holds the local master secret
+		PoolPublicKey:          c.memo.PoolPublicKey,
+		PoolContractPuzzleHash: c.memo.PoolContractPuzzleHash,
+		FarmerPublicKey:        c.memo.FarmerPublicKey,
+		LocalPublicKey:         c.localPublicKey,
+		PlotPublicKey:          c.plotPublicKey,
+		Index:                  c.index,
+		Quality:                c.quality,
+	}
+}
+
+// sendQuality is the candidate handler of the harvester, the farmer decides on the quality
+func (h *Harvester) sendQuality(ctx context.Context, miningInfo *entity2.MiningInfo, challengeBytes []byte, c *candidate) bool {
+	err := h.send(&remote.HarvesterMessage{Quality: qualityMessage(miningInfo, c)})
+	if err != nil {
+		logrus.Errorf("Failed to send quality of %v to the farmer %v", c.plotFile, err)
+	}
+	return false
+}
+
+// findPlot returns the farmed plot with the id and its space
+func (h *Harvester) findPlot(plotId []byte) (*Space, *plot) {
+	for _, space := range h.spaces {
+		for _, f := range space.getFiles() {
+			if bytes.Equal(f.GetId(), plotId) {
+				return space, f
+			}
+		}
+	}
+	return nil, nil
+}
+
+// serveProof reads a full proof requested by the farmer
+func (h *Harvester) serveProof(request *remote.ProofRequest) {
+	answer := &remote.Proof{Id: request.Id}
+	space, f := h.findPlot(request.PlotId)
+	if f == nil {
+		answer.Error = ErrPlotNotFound.Error()
+	} else {
+		proofTime := time.Now()
+		proof, err := f.fullProof(request.Challenge, request.Index, request.Quality)
+		metrics.FullProofSeconds.WithLabelValues(space.filepath).Observe(time.Since(proofTime).Seconds())
+		if err != nil {
+			answer.Error = err.Error()
+			logrus.Errorf("Failed to read proof %v %v", f.GetFilename(), err)
+		} else {
+			answer.Proof = proof
+			metrics.ProofsFetched.WithLabelValues(space.filepath).Inc()
+			logrus.Infof("Proof of %v sent to the farmer", f.GetFilename())
+		}
+	}
+	if err := h.send(&remote.HarvesterMessage{Proof: answer}); err != nil {
+		logrus.Errorf("Failed to send proof to the farmer %v", err)
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	entity2 "chia-miner/miner/entity"
 	"chia-miner/pkg/config"
+	"chia-miner/pkg/metrics"
+	"chia-miner/utils"
 	"compress/gzip"
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"github.com/pkg/errors"
@@ -14,17 +17,31 @@ import (
 	"io/ioutil"
 	"math"
 	"net/http"
+	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
+const (
+	miningInfoTimeout = 10 * time.Second
+	submitTimeout     = 30 * time.Second
+)
+
 var singleJsonRpc *JsonRpc
 
 func InitJsonRpc(cfg *config.Config) {
 	singleJsonRpc = &JsonRpc{
-		miningInfoClient: &http.Client{},
-		submitClient:     &http.Client{},
+		miningInfoClient: &http.Client{Timeout: miningInfoTimeout},
+		submitClient:     &http.Client{Timeout: submitTimeout},
 		cfg:              cfg,
+		staleHeight:      cfg.GetStaleHeight(),
+	}
+	for _, e := range cfg.GetRpcEndpoints() {
+		singleJsonRpc.endpoints = append(singleJsonRpc.endpoints, newEndpoint(e))
+	}
+	if len(singleJsonRpc.endpoints) > 1 {
+		utils.StartTime(singleJsonRpc.checkEndpoints, cfg.GetHealthCheckInterval()*1000)
 	}
 }
 
@@ -37,43 +54,212 @@ type JsonRpc struct {
 	jsonRpcId        int64
 	miningInfoClient *http.Client
 	submitClient     *http.Client
+	endpoints        []*endpoint
+	staleHeight      uint32
+	mutex            sync.Mutex
+	active           *endpoint
 }
 
+// GetMiningInfo gets the mining info of the preferred usable node, failing over
+// to the next one when it errors or is behind the other nodes
 func (j *JsonRpc) GetMiningInfo() (*entity2.MiningInfo, error) {
-	raw, err := j.call("pos_getMiningInfo", []interface{}{}, nil, j.miningInfoClient, nil)
+	return j.firstUsable(j.fetchMiningInfo)
+}
+
+// firstUsable gets the mining info from the candidates in order until a usable
+// node answers, fetch records the health of the node
+func (j *JsonRpc) firstUsable(fetch func(e *endpoint) (*entity2.MiningInfo, error)) (*entity2.MiningInfo, error) {
+	var lastErr error
+	for _, e := range j.candidates() {
+		miningInfo, err := fetch(e)
+		if err != nil {
+			lastErr = errors.Wrap(err, e.Url)
+			continue
+		}
+		j.updateHealth()
+		if !e.usable() {
+			lastErr = errors.Wrapf(ErrStaleHeight, "%v height %v", e.Url, miningInfo.Height)
+			continue
+		}
+		j.setActive(e)
+		return miningInfo, nil
+	}
+	j.updateHealth()
+	return nil, lastErr
+}
+
+func (j *JsonRpc) setActive(e *endpoint) {
+	j.mutex.Lock()
+	previous := j.active
+	j.active = e
+	j.mutex.Unlock()
+	if previous != nil && previous != e {
+		status := previous.getStatus()
+		reason := status.LastError
+		if status.Stale {
+			reason = ErrStaleHeight.Error()
+		}
+		if reason == "" {
+			reason = "preferred node recovered"
+		}
+		logrus.Warnf("Switched node from %v to %v at height %v, %v", previous.Url, e.Url, status.Height, reason)
+	}
+}
+
+// fetchMiningInfo gets the mining info of one node and records its health
+func (j *JsonRpc) fetchMiningInfo(e *endpoint) (*entity2.MiningInfo, error) {
+	raw, err := j.call(context.Background(), e, "pos_getMiningInfo", []interface{}{}, nil, j.miningInfoClient, nil)
 	if err != nil {
+		e.fail(err)
 		return nil, err
 	}
-	ret := gjson.Parse(raw)
-	challenge, _ := hex.DecodeString(ret.Get("result.challenge").String())
+	miningInfo, err := parseMiningInfo(gjson.Parse(raw).Get("result"))
+	if err != nil {
+		e.fail(err)
+		return nil, err
+	}
+	e.succeed(miningInfo.Height)
+	return miningInfo, nil
+}
+
+// WaitMiningInfo long-polls the preferred usable node, the node answers when its
+// mining info differs from last or after timeout. A failing node is marked
+// unhealthy and the next one is polled, like GetMiningInfo.
+func (j *JsonRpc) WaitMiningInfo(last *entity2.MiningInfo, timeout time.Duration) (*entity2.MiningInfo, error) {
+	params := []interface{}{0, "", 0, int64(timeout / time.Second)}
+	if last != nil {
+		params = []interface{}{last.Height, hex.EncodeToString(last.Challenge), last.ScanIterations, int64(timeout / time.Second)}
+	}
+	client := &http.Client{Timeout: timeout + 10*time.Second}
+	return j.firstUsable(func(e *endpoint) (*entity2.MiningInfo, error) {
+		raw, err := j.call(context.Background(), e, "pos_waitMiningInfo", params, nil, client, nil)
+		if err != nil {
+			// an rpc error is an answer, the node may not support long-polling
+			if _, ok := errors.Cause(err).(RawRpcError); !ok {
+				e.fail(err)
+			}
+			return nil, err
+		}
+		miningInfo, err := parseMiningInfo(gjson.Parse(raw).Get("result"))
+		if err != nil {
+			e.fail(err)
+			return nil, err
+		}
+		e.succeed(miningInfo.Height)
+		return miningInfo, nil
+	})
+}
+
+// parseMiningInfo parses the mining info returned or pushed by the node
+func parseMiningInfo(ret gjson.Result) (*entity2.MiningInfo, error) {
+	challenge, _ := hex.DecodeString(ret.Get("challenge").String())
 	if len(challenge) != 32 {
 		return nil, ErrBadMiningInfo
 	}
-	miningInfo := &entity2.MiningInfo{
-		Height:         uint32(ret.Get("result.height").Int()),
+	return &entity2.MiningInfo{
+		Height:         uint32(ret.Get("height").Int()),
 		Challenge:      challenge,
 		ReceiveTime:    time.Now().Unix(),
-		Difficulty:     ret.Get("result.difficulty").Uint(),
-		Epoch:          ret.Get("result.epoch").Int(),
-		FilterBits:     int(ret.Get("result.filter_bits").Int()),
-		ServerTime:     ret.Get("result.now").Int(),
-		ScanIterations: ret.Get("result.scan_iterations").Int(),
+		Difficulty:     ret.Get("difficulty").Uint(),
+		Epoch:          ret.Get("epoch").Int(),
+		FilterBits:     int(ret.Get("filter_bits").Int()),
+		ServerTime:     ret.Get("now").Int(),
+		ScanIterations: ret.Get("scan_iterations").Int(),
 		BestQuality:    math.MaxInt64,
+	}, nil
+}
+
+// Submit submits a signed proof to the preferred usable node, failing over to
+// the next one on transient errors, or to every healthy node if submitAll is set.
+// ctx cancels a request in flight.
+func (j *JsonRpc) Submit(ctx context.Context, info *entity2.SubmitProof) (result *entity2.SubmitResult, raw string, err error) {
+	if j.cfg.Rpc.SubmitAll && len(j.endpoints) > 1 {
+		return j.submitAll(ctx, info)
 	}
-	return miningInfo, nil
+	for _, e := range j.candidates() {
+		result, raw, err = j.submit(ctx, e, info)
+		// a cancelled request says nothing about the node
+		if err == nil || !isRetryable(err) || ctx.Err() != nil {
+			return result, raw, err
+		}
+		e.fail(err)
+		logrus.Warnf("Submit proof height %v to %v failed %v", info.Height, e.Url, err)
+	}
+	return nil, raw, err
+}
+
+// submitAll submits the proof to the healthy nodes concurrently, the answer
+// of the first node accepting it is returned
+func (j *JsonRpc) submitAll(ctx context.Context, info *entity2.SubmitProof) (*entity2.SubmitResult, string, error) {
+	list := j.healthy()
+	type answer struct {
+		result *entity2.SubmitResult
+		raw    string
+		err    error
+	}
+	answers := make([]answer, len(list))
+	var wg sync.WaitGroup
+	for i, e := range list {
+		wg.Add(1)
+		go func(i int, e *endpoint) {
+			defer wg.Done()
+			result, raw, err := j.submit(ctx, e, info)
+			if err != nil {
+				if isRetryable(err) && ctx.Err() == nil {
+					e.fail(err)
+				}
+				logrus.Warnf("Submit proof height %v to %v failed %v", info.Height, e.Url, err)
+			}
+			answers[i] = answer{result, raw, err}
+		}(i, e)
+	}
+	wg.Wait()
+
+	best := answers[0]
+	for _, a := range answers {
+		if a.err == nil && (best.err != nil || a.result.Accepted && !best.result.Accepted) {
+			best = a
+		}
+	}
+	return best.result, best.raw, best.err
 }
 
-func (j *JsonRpc) Submit(info *entity2.SubmitProof) {
-	raw, err := j.call("pos_submitProof", info, nil, j.submitClient, nil)
+// submit submits a signed proof to one node, the result is either a bool or an
+// object with the accepted flag and a message
+func (j *JsonRpc) submit(ctx context.Context, e *endpoint, info *entity2.SubmitProof) (*entity2.SubmitResult, string, error) {
+	raw, err := j.call(ctx, e, "pos_submitSignedProof", info, nil, j.submitClient, nil)
 	if err != nil {
-		logrus.Errorf("submitProof fail, error %v", err)
-		return
+		return nil, raw, err
+	}
+	logrus.Debugf("submitSignedProof %v %v", e.Url, raw)
+	ret := gjson.Parse(raw).Get("result")
+	result := &entity2.SubmitResult{}
+	switch {
+	case ret.IsObject():
+		result.Accepted = ret.Get("accepted").Bool()
+		result.Message = ret.Get("message").String()
+	case ret.Type == gjson.True || ret.Type == gjson.False:
+		result.Accepted = ret.Bool()
+	case ret.Type == gjson.String:
+		// the node returns a message
+		result.Accepted = true
+		result.Message = ret.String()
+	default:
+		result.Accepted = ret.Exists()
 	}
-	logrus.Infof("submitProof %v", raw)
+	return result, raw, nil
 }
 
-// CallJsonRpc call json rpc method
-func (j *JsonRpc) call(method string, params interface{}, result interface{}, rpcClient *http.Client, headers map[string]string) (raw string, err error) {
+// CallJsonRpc call json rpc method, ctx cancels the request
+func (j *JsonRpc) call(ctx context.Context, e *endpoint, method string, params interface{}, result interface{}, rpcClient *http.Client, headers map[string]string) (raw string, err error) {
+	startTime := time.Now()
+	defer func() {
+		metrics.RpcRequestSeconds.WithLabelValues(method).Observe(time.Since(startTime).Seconds())
+		if err != nil {
+			metrics.RpcErrors.WithLabelValues(method, rpcErrorCode(err)).Inc()
+		}
+	}()
+
 	var body io.Reader
 	if data, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params, "id": atomic.AddInt64(&j.jsonRpcId, 1)}); err != nil {
 		return "", err
@@ -82,7 +268,7 @@ func (j *JsonRpc) call(method string, params interface{}, result interface{}, rp
 		body = bytes.NewReader(data)
 	}
 
-	request, err := http.NewRequest(http.MethodPost, j.cfg.Rpc.Url, body)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Url, body)
 	if err != nil {
 		return "", err
 	}
@@ -91,7 +277,7 @@ func (j *JsonRpc) call(method string, params interface{}, result interface{}, rp
 		request.Header.Set(k, v)
 	}
 	request.Header.Set("Content-Type", "application/json; charset=utf-8")
-	request.Header.Set("Authorization", j.cfg.GetAuthorizationToken())
+	request.Header.Set("Authorization", e.GetAuthorizationToken())
 	if response, err := rpcClient.Do(request); err != nil {
 		return "", err
 	} else if response.StatusCode == http.StatusUnauthorized {
@@ -136,6 +322,25 @@ func (j *JsonRpc) call(method string, params interface{}, result interface{}, rp
 		}
 	}
 }
+
+// rpcErrorCode returns the code of a RawRpcError, or the kind of failure for other errors
+func rpcErrorCode(err error) string {
+	switch cause := errors.Cause(err).(type) {
+	case RawRpcError:
+		return strconv.Itoa(cause.Code())
+	default:
+		switch cause {
+		case ErrUnauthenticated:
+			return "unauthenticated"
+		case ErrPermissionDenied:
+			return "permission_denied"
+		case ErrBadRequest:
+			return "bad_request"
+		}
+	}
+	return "error"
+}
+
 func readResponseBody(response *http.Response) ([]byte, error) {
 	defer response.Body.Close()
 
@@ -0,0 +1,44 @@
+package miner
+
+import (
+	"chia-miner/pkg/config"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWaitMiningInfoFailover a long-poll against a failing node marks it
+// unhealthy and polls the next node
+func TestWaitMiningInfoFailover(t *testing.T) {
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer dead.Close()
+	challenge := strings.Repeat("ab", 32)
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":{"height":7,"challenge":"%v","difficulty":1,"scan_iterations":3}}`, challenge)
+	}))
+	defer backup.Close()
+
+	cfg := &config.Config{}
+	cfg.Rpc.Endpoints = []config.RpcEndpoint{{Url: dead.URL, Priority: 0}, {Url: backup.URL, Priority: 1}}
+	j := &JsonRpc{cfg: cfg, staleHeight: cfg.GetStaleHeight()}
+	for _, e := range cfg.GetRpcEndpoints() {
+		j.endpoints = append(j.endpoints, newEndpoint(e))
+	}
+
+	miningInfo, err := j.WaitMiningInfo(nil, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if miningInfo.Height != 7 {
+		t.Fatalf("height %v, want 7", miningInfo.Height)
+	}
+	endpoints := j.GetEndpoints()
+	if endpoints[0].Healthy || endpoints[0].Failures != 1 || !endpoints[1].Healthy || !endpoints[1].Active {
+		t.Fatalf("endpoints %+v", endpoints)
+	}
+}
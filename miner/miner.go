@@ -4,12 +4,20 @@ import (
 	"bytes"
 	"chia-miner/miner/entity"
 	"chia-miner/pkg/config"
+	"chia-miner/pkg/history"
+	"chia-miner/pkg/metrics"
 	"chia-miner/utils"
+	"context"
 	"encoding/hex"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"math"
+	"sync"
 	"time"
 )
 
+const defaultPlotReloadInterval = 300
+
 var singleMiner *Miner
 
 func init() {
@@ -26,28 +34,190 @@ type Miner struct {
 	miningInfo     *entity.MiningInfo
 	scanIterations int64
 	scanTime       int64
+	submitter      *submitter
+	history        *history.DB
+	farmer         *farmerServer
+	push           *pushClient
+	pool           *poolClient
+	mutex          sync.RWMutex
+
+	// scanCtx is cancelled when the challenge is superseded
+	scanCtx    context.Context
+	cancelScan context.CancelFunc
 }
 
-func (m *Miner) Start(config *config.Config) {
+func (m *Miner) Start(config *config.Config) error {
 	m.config = config
+	m.submitter = newSubmitter()
+	if !config.History.Disable {
+		db, err := history.Open(config.GetHistoryFile())
+		if err != nil {
+			logrus.Errorf("Failed to open history database %v", err)
+		}
+		m.history = db
+	}
 	InitJsonRpc(config)
+	sched := newScheduler(config)
+	index := newPlotIndex(config)
 	for _, filepath := range m.config.Path {
-		m.spaces = append(m.spaces, NewSpace(filepath, config))
+		m.spaces = append(m.spaces, NewSpace(filepath, config, sched, index))
+	}
+	if missing := m.logKeyAudit(); missing > 0 && config.MissingFarmerKey == "refuse" {
+		return errors.Wrapf(ErrMissingFarmerKey, "%v plots", missing)
+	}
+	m.updatePlotMetrics()
+	if config.Role == "farmer" {
+		if err := m.startFarmer(); err != nil {
+			return err
+		}
+	}
+
+	if config.Pool.Url != "" {
+		pool, err := newPoolClient(config)
+		if err != nil {
+			return err
+		}
+		m.pool = pool
+		pool.start()
+	}
+	if config.Rpc.Push.Mode != "" {
+		push, err := newPushClient(config, m.updateMiningInfo)
+		if err != nil {
+			return err
+		}
+		m.push = push
+		go push.run()
+	}
+	utils.StartTime(m.onTimer, config.GetPollInterval())
+
+	reloadInterval := m.config.PlotReloadInterval
+	if reloadInterval == 0 {
+		reloadInterval = defaultPlotReloadInterval
+	}
+	if reloadInterval > 0 {
+		utils.StartTime(m.ReloadPlots, reloadInterval*1000)
+	}
+	return nil
+}
+
+// ReloadPlots rescans the plot directories of every space
+func (m *Miner) ReloadPlots() {
+	for _, space := range m.spaces {
+		space.reload()
+	}
+	m.logKeyAudit()
+	m.updatePlotMetrics()
+}
+
+// Rescan scans the current challenge again
+func (m *Miner) Rescan() error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if m.miningInfo == nil {
+		return ErrNotFoundData
+	}
+	miningInfo := *m.miningInfo
+	miningInfo.BestQuality = math.MaxInt64
+	for _, space := range m.spaces {
+		space.requestScan(m.scanCtx, &miningInfo)
+	}
+	msg := challengeMessage(&miningInfo)
+	msg.Challenge.Rescan = true
+	m.farmer.broadcastMessage(msg)
+	logrus.Infof("rescan: height%v challenge[%v]", miningInfo.Height, hex.EncodeToString(miningInfo.Challenge))
+	return nil
+}
+
+// updateMetrics updates the plot and chain gauges
+func (m *Miner) updateMetrics() {
+	m.updatePlotMetrics()
+	metrics.Height.Set(float64(m.miningInfo.Height))
+	metrics.Difficulty.Set(float64(m.miningInfo.Difficulty))
+}
+
+// updatePlotMetrics updates the plot gauges of the local spaces and the harvesters
+func (m *Miner) updatePlotMetrics() {
+	var plots int
+	var space uint64
+	for _, status := range m.GetSpaceStatus() {
+		plots += status.Plots
+		space += status.RawSpace
+	}
+	for _, status := range m.GetHarvesters() {
+		plots += status.Plots
+		space += status.RawSpace
+	}
+	metrics.Plots.Set(float64(plots))
+	metrics.SpaceBytes.Set(float64(space))
+}
+
+// GetMiningInfo returns the current mining info, nil before the first one is received
+func (m *Miner) GetMiningInfo() *entity.MiningInfo {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.miningInfo
+}
+
+// GetSpaceStatus returns the status of every plot directory
+func (m *Miner) GetSpaceStatus() []*SpaceStatus {
+	list := make([]*SpaceStatus, 0, len(m.spaces))
+	for _, space := range m.spaces {
+		list = append(list, space.Status())
 	}
+	return list
+}
 
-	utils.StartTime(m.onTimer, 1000)
+// GetSubmissions returns the most recent submitted proofs
+func (m *Miner) GetSubmissions() []*SubmitRecord {
+	return m.submitter.records.list()
 }
+
+// GetHarvesters returns the connected remote harvesters
+func (m *Miner) GetHarvesters() []*HarvesterStatus {
+	return m.farmer.list()
+}
+
+// GetPoolStats returns the pool state and partial counters, nil when not pooling
+func (m *Miner) GetPoolStats() *PoolStats {
+	if m.pool == nil {
+		return nil
+	}
+	stats := m.pool.getStats()
+	return &stats
+}
+
+// GetSubmitStats returns the submission counters
+func (m *Miner) GetSubmitStats() SubmitStats {
+	return m.submitter.getStats()
+}
+
+// onTimer polls the mining info while no push channel is connected
 func (m *Miner) onTimer() {
+	if m.push.isConnected() {
+		return
+	}
 	miningInfo, err := GetJsonRpc().GetMiningInfo()
 	if err != nil {
 		logrus.Errorf("error getting mining info, please check server config %v", err)
 		return
 	}
+	m.updateMiningInfo(miningInfo)
+}
 
+// updateMiningInfo starts scanning new mining info, polled or pushed by the node
+func (m *Miner) updateMiningInfo(miningInfo *entity.MiningInfo) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	needScan := false
 	if m.miningInfo == nil || !m.miningInfo.IsSame(miningInfo) {
 		m.scanTime = time.Now().Unix()
 		needScan = true
+		if m.miningInfo == nil || !bytes.Equal(m.miningInfo.Challenge, miningInfo.Challenge) {
+			if m.cancelScan != nil {
+				m.cancelScan()
+			}
+			m.scanCtx, m.cancelScan = context.WithCancel(context.Background())
+		}
 		if m.miningInfo != nil && !bytes.Equal(m.miningInfo.Challenge, miningInfo.Challenge) {
 			m.scanIterations = 0
 		} else {
@@ -55,12 +225,21 @@ func (m *Miner) onTimer() {
 		}
 		m.miningInfo = miningInfo
 		m.miningInfo.ScanIterations = m.scanIterations
+		m.history.AddChallenge(&history.Challenge{
+			Height:         miningInfo.Height,
+			Challenge:      hex.EncodeToString(miningInfo.Challenge),
+			Difficulty:     miningInfo.Difficulty,
+			FilterBits:     miningInfo.FilterBits,
+			ScanIterations: miningInfo.ScanIterations,
+		})
 	}
 
 	if needScan {
 		for _, space := range m.spaces {
-			space.requestScan(m.miningInfo)
+			space.requestScan(m.scanCtx, m.miningInfo)
 		}
+		m.farmer.broadcastMessage(challengeMessage(m.miningInfo))
+		m.updateMetrics()
 		logrus.Infof("new block: height%v difficulty[%v] challenge[%v] scanIterations[%v] ",
 			m.miningInfo.Height, m.miningInfo.Difficulty, hex.EncodeToString(m.miningInfo.Challenge),
 			m.miningInfo.ScanIterations)
@@ -0,0 +1,65 @@
+package miner
+
+import (
+	"bytes"
+	export "chia-miner/export"
+	"chia-miner/pkg/bls"
+	chiapos2 "chia-miner/pkg/chiapos"
+	"chia-miner/pkg/metrics"
+	"sync"
+)
+
+// plot a loaded plot file and the device storing it
+type plot struct {
+	*chiapos2.File
+	device string
+
+	keysOnce       sync.Once
+	localPublicKey []byte
+	plotPublicKey  []byte
+	keysErr        error
+}
+
+// publicKeys returns the local public key and the plot public key, derived
+// once from the memo, so the local master secret never leaves the host
+func (f *plot) publicKeys() (localPk, plotPk []byte, err error) {
+	f.keysOnce.Do(func() {
+		f.localPublicKey, f.plotPublicKey, f.keysErr = plotPublicKeys(f.GetPlotMemo())
+	})
+	return f.localPublicKey, f.plotPublicKey, f.keysErr
+}
+
+// plotPublicKeys returns the local public key of the memo and the plot public
+// key, local public key + farmer public key
+func plotPublicKeys(memo *chiapos2.Memo) ([]byte, []byte, error) {
+	localPk, err := export.GetLocalPublicKey(memo.LocalMasterSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+	farmerPk, err := bls.PublicKeyFromBytes(memo.FarmerPublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	plotPk, err := export.GetPlotPublicKey(localPk, farmerPk, memo.IsPoolContract())
+	if err != nil {
+		return nil, nil, err
+	}
+	localBytes, _ := localPk.MarshalBinary()
+	plotBytes, err := plotPk.MarshalBinary()
+	return localBytes, plotBytes, err
+}
+
+// fullProof reads the full proof of a quality and verifies it, so a corrupted plot
+// region never produces a rejected submission. quality is not compared when nil.
+func (f *plot) fullProof(challenge []byte, index int, quality []byte) ([]byte, error) {
+	proof, ok := f.GetFullProof(challenge, index)
+	if !ok {
+		return nil, ErrReadProof
+	}
+	verified, ok := chiapos2.VerifyProof(f.GetId(), f.GetSize(), challenge, proof)
+	if !ok || quality != nil && !bytes.Equal(verified, quality) {
+		metrics.InvalidProofs.WithLabelValues(f.device).Inc()
+		return nil, ErrInvalidProof
+	}
+	return proof, nil
+}
@@ -0,0 +1,274 @@
+package miner
+
+import (
+	"bytes"
+	"chia-miner/miner/entity"
+	"chia-miner/pkg/bls"
+	chiapos2 "chia-miner/pkg/chiapos"
+	"chia-miner/pkg/config"
+	"chia-miner/pkg/metrics"
+	"chia-miner/utils"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	poolRefreshInterval = 600 // seconds
+	poolRequestTimeout  = 30 * time.Second
+	// defaultTokenTimeout minutes an authentication token is valid, unless the pool tells otherwise
+	defaultTokenTimeout = 5
+)
+
+// PoolStats state of the pool and counters of the partials submitted to it
+type PoolStats struct {
+	Url         string      `json:"url"`
+	Name        string      `json:"name"`
+	Difficulty  uint64      `json:"difficulty"`
+	Points      uint64      `json:"points"`       // points of the farmer reported by the pool
+	PointsFound uint64      `json:"points_found"` // difficulty of the partials accepted since start
+	Submitted   int         `json:"submitted"`
+	Accepted    int         `json:"accepted"`
+	Rejected    int         `json:"rejected"`
+	Failed      int         `json:"failed"`
+	ErrorCodes  map[int]int `json:"error_codes"` // pool error code -> count
+	LastError   string      `json:"last_error,omitempty"`
+}
+
+// poolClient submits the partials of the plots of a pool contract
+type poolClient struct {
+	cfg                *config.Config
+	client             *http.Client
+	launcherId         []byte
+	contractPuzzleHash []byte
+	authKey            *bls.PrivateKey
+
+	mutex        sync.Mutex
+	tokenTimeout uint64
+	stats        PoolStats
+}
+
+func newPoolClient(cfg *config.Config) (*poolClient, error) {
+	p := &poolClient{
+		cfg:          cfg,
+		client:       &http.Client{Timeout: poolRequestTimeout},
+		tokenTimeout: defaultTokenTimeout,
+		stats: PoolStats{
+			Url:        cfg.Pool.Url,
+			ErrorCodes: make(map[int]int),
+		},
+	}
+	var err error
+	if p.launcherId, err = hex.DecodeString(strings.TrimPrefix(cfg.Pool.LauncherId, "0x")); err != nil || len(p.launcherId) != 32 {
+		return nil, errors.Wrap(ErrBadPoolConfig, "launcherId must be 32 bytes hex")
+	}
+	if cfg.Pool.ContractPuzzleHash != "" {
+		if p.contractPuzzleHash, err = hex.DecodeString(strings.TrimPrefix(cfg.Pool.ContractPuzzleHash, "0x")); err != nil || len(p.contractPuzzleHash) != 32 {
+			return nil, errors.Wrap(ErrBadPoolConfig, "contractPuzzleHash must be 32 bytes hex")
+		}
+	}
+	if cfg.Pool.AuthenticationKey != "" {
+		if p.authKey, err = bls.PrivateKeyFromHex(strings.TrimPrefix(cfg.Pool.AuthenticationKey, "0x")); err != nil {
+			return nil, errors.Wrapf(ErrBadPoolConfig, "authenticationKey %v", err)
+		}
+	}
+	return p, nil
+}
+
+// start fetches the pool difficulty now and periodically
+func (p *poolClient) start() {
+	go p.refresh()
+	utils.StartTime(p.refresh, poolRefreshInterval*1000)
+}
+
+// authenticationToken returns the current authentication token, it changes
+// every tokenTimeout minutes
+func (p *poolClient) authenticationToken() uint64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return uint64(time.Now().Unix()) / 60 / p.tokenTimeout
+}
+
+// refresh gets the pool info, and the difficulty and points of the farmer
+// when an authentication key is configured
+func (p *poolClient) refresh() {
+	info := struct {
+		Name                       string `json:"name"`
+		MinimumDifficulty          uint64 `json:"minimum_difficulty"`
+		AuthenticationTokenTimeout uint64 `json:"authentication_token_timeout"`
+	}{}
+	if err := p.request(http.MethodGet, "/pool_info", nil, &info); err != nil {
+		logrus.Errorf("Failed to get pool info %v %v", p.cfg.Pool.Url, err)
+		p.setError(err)
+		return
+	}
+	p.mutex.Lock()
+	p.stats.Name = info.Name
+	if info.AuthenticationTokenTimeout > 0 {
+		p.tokenTimeout = info.AuthenticationTokenTimeout
+	}
+	if p.stats.Difficulty == 0 {
+		p.stats.Difficulty = info.MinimumDifficulty
+	}
+	p.mutex.Unlock()
+
+	if p.authKey != nil {
+		token := p.authenticationToken()
+		signature, err := p.authKey.SignMessage(entity.AuthenticationMessage("get_farmer", p.launcherId, token, nil))
+		if err != nil {
+			logrus.Errorf("Failed to sign pool request %v", err)
+			return
+		}
+		query := url.Values{}
+		query.Set("launcher_id", hex.EncodeToString(p.launcherId))
+		query.Set("authentication_token", strconv.FormatUint(token, 10))
+		query.Set("signature", hex.EncodeToString(signature))
+		farmer := struct {
+			CurrentDifficulty uint64 `json:"current_difficulty"`
+			CurrentPoints     uint64 `json:"current_points"`
+		}{}
+		if err := p.request(http.MethodGet, "/farmer?"+query.Encode(), nil, &farmer); err != nil {
+			logrus.Errorf("Failed to get farmer from pool %v %v", p.cfg.Pool.Url, err)
+			p.setError(err)
+		} else {
+			p.mutex.Lock()
+			if farmer.CurrentDifficulty > 0 {
+				p.stats.Difficulty = farmer.CurrentDifficulty
+			}
+			p.stats.Points = farmer.CurrentPoints
+			p.mutex.Unlock()
+		}
+	}
+
+	stats := p.getStats()
+	metrics.PoolDifficulty.Set(float64(stats.Difficulty))
+	metrics.PoolPoints.Set(float64(stats.Points))
+	logrus.Infof("Pool %v difficulty %v points %v", stats.Name, stats.Difficulty, stats.Points)
+}
+
+// difficultyFor returns the pool difficulty of a plot, 0 if the plot is not farmed for the pool
+func (p *poolClient) difficultyFor(memo *chiapos2.Memo) uint64 {
+	if p == nil || !memo.IsPoolContract() {
+		return 0
+	}
+	if len(p.contractPuzzleHash) > 0 && !bytes.Equal(p.contractPuzzleHash, memo.PoolContractPuzzleHash) {
+		return 0
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.stats.Difficulty
+}
+
+// submitPartial submits a signed proof meeting the pool difficulty in the
+// background, the partial is signed with the authentication key or the farmer key
+func (p *poolClient) submitPartial(proof *entity.SubmitProof, farmerKey *bls.PrivateKey, difficulty uint64) {
+	key := p.authKey
+	if key == nil {
+		key = farmerKey
+	}
+	partial := &entity.PostPartial{
+		LauncherId:          hex.EncodeToString(p.launcherId),
+		AuthenticationToken: p.authenticationToken(),
+		Proof:               entity.NewPartialProof(proof),
+	}
+	if err := partial.Sign(key); err != nil {
+		logrus.Errorf("Failed to sign partial, plot %v error %v", proof.PlotId, err)
+		return
+	}
+	p.mutex.Lock()
+	p.stats.Submitted++
+	p.mutex.Unlock()
+
+	go func() {
+		result := struct {
+			NewDifficulty uint64 `json:"new_difficulty"`
+			ErrorCode     int    `json:"error_code"`
+			ErrorMessage  string `json:"error_message"`
+		}{}
+		err := p.request(http.MethodPost, "/partial", partial, &result)
+
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		switch {
+		case err != nil:
+			p.stats.Failed++
+			p.stats.LastError = err.Error()
+			metrics.PoolPartials.WithLabelValues("failed").Inc()
+			logrus.Errorf("Submit partial height %v plot %v failed %v", proof.Height, proof.PlotId, err)
+		case result.ErrorCode != 0:
+			p.stats.Rejected++
+			p.stats.ErrorCodes[result.ErrorCode]++
+			p.stats.LastError = result.ErrorMessage
+			metrics.PoolPartials.WithLabelValues("rejected").Inc()
+			logrus.Warnf("Partial rejected, height %v plot %v code %v message %v",
+				proof.Height, proof.PlotId, result.ErrorCode, result.ErrorMessage)
+		default:
+			p.stats.Accepted++
+			p.stats.PointsFound += difficulty
+			metrics.PoolPartials.WithLabelValues("accepted").Inc()
+			if result.NewDifficulty > 0 && result.NewDifficulty != p.stats.Difficulty {
+				logrus.Infof("Pool difficulty changed from %v to %v", p.stats.Difficulty, result.NewDifficulty)
+				p.stats.Difficulty = result.NewDifficulty
+				metrics.PoolDifficulty.Set(float64(result.NewDifficulty))
+			}
+			logrus.Infof("Partial accepted, height %v plot %v difficulty %v", proof.Height, proof.PlotId, difficulty)
+		}
+	}()
+}
+
+// request calls the pool api, body is sent as json and the answer decoded into result
+func (p *poolClient) request(method string, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	request, err := http.NewRequest(method, strings.TrimSuffix(p.cfg.Pool.Url, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	response, err := p.client.Do(request)
+	if err != nil {
+		return err
+	}
+	data, err := readResponseBody(response)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return errors.Wrapf(ErrBadRequest, "status code %d %s", response.StatusCode, data)
+	}
+	return json.Unmarshal(data, result)
+}
+
+func (p *poolClient) setError(err error) {
+	p.mutex.Lock()
+	p.stats.LastError = err.Error()
+	p.mutex.Unlock()
+}
+
+func (p *poolClient) getStats() PoolStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	stats := p.stats
+	stats.ErrorCodes = make(map[int]int, len(p.stats.ErrorCodes))
+	for code, count := range p.stats.ErrorCodes {
+		stats.ErrorCodes[code] = count
+	}
+	return stats
+}
@@ -0,0 +1,165 @@
+package miner
+
+import (
+	"chia-miner/miner/entity"
+	"chia-miner/pkg/config"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	pushWebsocket = "websocket"
+	pushLongPoll  = "longpoll"
+)
+
+// pushClient receives the mining info pushed by the node, the miner polls
+// while it is not connected
+type pushClient struct {
+	cfg       *config.Config
+	connected int32
+	handle    func(miningInfo *entity.MiningInfo)
+}
+
+func newPushClient(cfg *config.Config, handle func(miningInfo *entity.MiningInfo)) (*pushClient, error) {
+	switch cfg.Rpc.Push.Mode {
+	case pushWebsocket:
+		if cfg.Rpc.Push.Url == "" {
+			return nil, errors.New("websocket push needs rpc.push.url")
+		}
+	case pushLongPoll:
+	default:
+		return nil, errors.Errorf("unknown push mode %v", cfg.Rpc.Push.Mode)
+	}
+	return &pushClient{cfg: cfg, handle: handle}, nil
+}
+
+func (p *pushClient) isConnected() bool {
+	return p != nil && atomic.LoadInt32(&p.connected) == 1
+}
+
+// run keeps the push channel open, reconnecting with a backoff
+func (p *pushClient) run() {
+	delay := time.Second
+	for {
+		start := time.Now()
+		var err error
+		if p.cfg.Rpc.Push.Mode == pushWebsocket {
+			err = p.subscribe()
+		} else {
+			err = p.longPoll()
+		}
+		atomic.StoreInt32(&p.connected, 0)
+		if time.Since(start) > maxReconnectDelay {
+			delay = time.Second
+		}
+		logrus.Warnf("Mining info %v push unavailable %v, polling every %vms, retry in %v",
+			p.cfg.Rpc.Push.Mode, err, p.cfg.GetPollInterval(), delay)
+		time.Sleep(delay)
+		if delay *= 2; delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// longPoll waits for new mining info until the node fails to answer
+func (p *pushClient) longPoll() error {
+	var last *entity.MiningInfo
+	for {
+		miningInfo, err := GetJsonRpc().WaitMiningInfo(last, p.cfg.GetPushTimeout())
+		if err != nil {
+			return err
+		}
+		if atomic.CompareAndSwapInt32(&p.connected, 0, 1) {
+			logrus.Infof("Mining info long-poll connected")
+		}
+		// handle may rewrite the scan iterations, the next wait sends the node's values
+		node := *miningInfo
+		last = &node
+		p.handle(miningInfo)
+	}
+}
+
+// subscribe subscribes to the mining info over a websocket, the node sends a
+// notification whenever the challenge or the scan iterations change
+func (p *pushClient) subscribe() error {
+	wsConfig, err := websocket.NewConfig(p.cfg.Rpc.Push.Url, "http://localhost/")
+	if err != nil {
+		return err
+	}
+	if endpoints := p.cfg.GetRpcEndpoints(); len(endpoints) > 0 {
+		if token := endpoints[0].GetAuthorizationToken(); token != "" {
+			wsConfig.Header.Set("Authorization", token)
+		}
+	}
+	conn, err := websocket.DialConfig(wsConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	request, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "pos_subscribe", "params": []string{"miningInfo"}})
+	if err := websocket.Message.Send(conn, string(request)); err != nil {
+		return err
+	}
+	// a half-open connection is detected by the read deadline, the heartbeat
+	// requests keep a quiet connection answering
+	timeout := p.cfg.GetPushTimeout()
+	done := make(chan struct{})
+	defer close(done)
+	go p.heartbeat(conn, timeout/3, done)
+	for {
+		var raw string
+		if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			return err
+		}
+		if err := websocket.Message.Receive(conn, &raw); err != nil {
+			return err
+		}
+		ret := gjson.Parse(raw)
+		if id := ret.Get("id"); id.Exists() && id.Int() != 1 {
+			// heartbeat answer
+			continue
+		}
+		if rpcErr := ret.Get("error"); rpcErr.Exists() && rpcErr.Type != gjson.Null {
+			return WrapRawRpcError(int(rpcErr.Get("code").Int()), rpcErr.Get("message").String())
+		}
+		if ret.Get("id").Exists() {
+			atomic.StoreInt32(&p.connected, 1)
+			logrus.Infof("Subscribed to mining info %v, subscription %v", p.cfg.Rpc.Push.Url, ret.Get("result").String())
+			continue
+		}
+		if ret.Get("method").String() != "pos_subscription" {
+			continue
+		}
+		miningInfo, err := parseMiningInfo(ret.Get("params.result"))
+		if err != nil {
+			return err
+		}
+		p.handle(miningInfo)
+	}
+}
+
+// heartbeat requests the mining info every interval until done, any answer
+// moves the read deadline of the subscription
+func (p *pushClient) heartbeat(conn *websocket.Conn, interval time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for id := 2; ; id++ {
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+		request, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": "pos_getMiningInfo", "params": []interface{}{}})
+		if err := websocket.Message.Send(conn, string(request)); err != nil {
+			logrus.Debugf("Mining info heartbeat failed %v", err)
+			return
+		}
+	}
+}
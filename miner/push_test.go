@@ -0,0 +1,67 @@
+package miner
+
+import (
+	"chia-miner/miner/entity"
+	"chia-miner/pkg/config"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"golang.org/x/net/websocket"
+)
+
+// testPushNode accepts the subscription, then answers the heartbeats when alive
+// or stops answering like a half-open connection
+func testPushNode(alive bool) *httptest.Server {
+	return httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		for {
+			var raw string
+			if err := websocket.Message.Receive(conn, &raw); err != nil {
+				return
+			}
+			id := gjson.Get(raw, "id").Int()
+			if id != 1 && !alive {
+				continue
+			}
+			if err := websocket.Message.Send(conn, `{"jsonrpc":"2.0","id":`+gjson.Get(raw, "id").Raw+`,"result":"0x1"}`); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestSubscribeDeadline(t *testing.T) {
+	for _, alive := range []bool{false, true} {
+		node := testPushNode(alive)
+		cfg := &config.Config{}
+		cfg.Rpc.Push.Mode = pushWebsocket
+		cfg.Rpc.Push.Url = "ws" + strings.TrimPrefix(node.URL, "http")
+		cfg.Rpc.Push.Timeout = 1
+		p, err := newPushClient(cfg, func(miningInfo *entity.MiningInfo) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan error, 1)
+		go func() {
+			done <- p.subscribe()
+		}()
+		select {
+		case err := <-done:
+			if alive {
+				t.Fatalf("subscription of a live node closed %v", err)
+			}
+		case <-time.After(3 * time.Second):
+			if !alive {
+				t.Fatal("subscription of a silent node still open")
+			}
+			if atomic.LoadInt32(&p.connected) != 1 {
+				t.Fatal("subscription not connected")
+			}
+		}
+		node.CloseClientConnections()
+		node.Close()
+	}
+}
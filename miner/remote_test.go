@@ -0,0 +1,237 @@
+package miner
+
+import (
+	"bytes"
+	"chia-miner/miner/entity"
+	chiapos2 "chia-miner/pkg/chiapos"
+	"chia-miner/pkg/config"
+	"chia-miner/pkg/metrics"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// testPlotPath holds the k14 fixture plot of the chiapos tests
+const testPlotPath = "../pkg/chiapos/testdata"
+
+// testChallenge returns a challenge the fixture plot has qualities for
+func testChallenge(t *testing.T, scanIterations int64) []byte {
+	f, err := chiapos2.Open(testPlotPath + "/k14.plot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := byte(0); i < 255; i++ {
+		challenge := sha256.Sum256([]byte{i})
+		if qualities, _ := f.GetQualitiesForChallenge(plotChallenge(challenge[:], scanIterations)); len(qualities) > 0 {
+			return challenge[:]
+		}
+	}
+	t.Fatal("no challenge with qualities")
+	return nil
+}
+
+// writeTestCert writes a self-signed certificate of 127.0.0.1 and its key
+func writeTestCert(t *testing.T) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "farmer"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "farmer.crt"), filepath.Join(dir, "farmer.key")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// TestFarmerToken a farmer listening on all interfaces needs a token
+func TestFarmerToken(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Farmer.Listen = "0.0.0.0:0"
+	m := &Miner{config: cfg}
+	if err := m.startFarmer(); errors.Cause(err) != ErrFarmerToken {
+		t.Fatalf("startFarmer = %v, want %v", err, ErrFarmerToken)
+	}
+}
+
+// TestFarmerHarvester connects a harvester to a farmer on loopback, the
+// farmer sends a challenge, the harvester scans its plot and reports a
+// quality, and the farmer reads the full proof from the harvester
+func TestFarmerHarvester(t *testing.T) {
+	t.Run("plain", func(t *testing.T) {
+		testFarmerHarvester(t, false)
+	})
+	t.Run("tls", func(t *testing.T) {
+		testFarmerHarvester(t, true)
+	})
+}
+
+// startLoopback starts a farmer and connects a harvester farming the fixture
+// plot, the candidates of the farmer are returned on the channel
+func startLoopback(t *testing.T, farmerCfg *config.Config) (*Miner, chan *candidate) {
+	farmerCfg.Farmer.Listen = "127.0.0.1:0"
+	farmerCfg.Farmer.Token = "secret"
+	m := &Miner{config: farmerCfg}
+	if err := m.startFarmer(); err != nil {
+		t.Fatal(err)
+	}
+	candidates := make(chan *candidate, 16)
+	m.farmer.handle = func(ctx context.Context, miningInfo *entity.MiningInfo, challengeBytes []byte, c *candidate) bool {
+		candidates <- c
+		return false
+	}
+
+	// the harvester has no farmer keys, it farms the plot whatever missingFarmerKey says
+	harvesterCfg := &config.Config{Path: []string{testPlotPath}, PlotReloadInterval: -1, Role: "harvester", MissingFarmerKey: "exclude"}
+	harvesterCfg.Harvester.Farmer = m.farmer.address
+	harvesterCfg.Harvester.Name = "loopback"
+	harvesterCfg.Harvester.Token = "secret"
+	harvesterCfg.Harvester.CaFile = farmerCfg.Farmer.CertFile
+	if err := NewHarvester(harvesterCfg).Start(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for len(m.GetHarvesters()) == 0 || m.GetHarvesters()[0].Plots == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("harvester did not connect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status := m.GetHarvesters()[0]; status.Name != "loopback" || status.Plots != 1 {
+		t.Fatalf("harvester %+v", status)
+	}
+	return m, candidates
+}
+
+func testFarmerHarvester(t *testing.T, tls bool) {
+	farmerCfg := &config.Config{}
+	if tls {
+		farmerCfg.Farmer.CertFile, farmerCfg.Farmer.KeyFile = writeTestCert(t)
+	}
+	m, candidates := startLoopback(t, farmerCfg)
+
+	miningInfo := &entity.MiningInfo{Height: 1, Challenge: testChallenge(t, 1), Difficulty: 1, ScanIterations: 1}
+	m.mutex.Lock()
+	m.miningInfo = miningInfo
+	m.scanCtx, m.cancelScan = context.WithCancel(context.Background())
+	m.mutex.Unlock()
+	defer m.cancelScan()
+	m.farmer.broadcastMessage(challengeMessage(miningInfo))
+
+	select {
+	case c := <-candidates:
+		if c.size != 14 || c.memo == nil || len(c.quality) != 32 {
+			t.Fatalf("candidate %+v", c)
+		}
+		// the farmer gets the plot public key, never the local master secret
+		if len(c.plotPublicKey) != 48 || c.memo.LocalMasterSecret != nil {
+			t.Fatalf("candidate keys %+v", c.memo)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		served := testutil.ToFloat64(metrics.ProofsFetched.WithLabelValues(testPlotPath))
+		proof, err := c.fetchProof(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if testutil.ToFloat64(metrics.ProofsFetched.WithLabelValues(testPlotPath)) != served+1 {
+			t.Fatal("served proof not counted")
+		}
+		quality, ok := chiapos2.VerifyProof(c.plotId, c.size, plotChallenge(miningInfo.Challenge, miningInfo.ScanIterations), proof)
+		if !ok || !bytes.Equal(quality, c.quality) {
+			t.Fatal("invalid proof from the harvester")
+		}
+
+		// a proof that does not match the reported quality is refused
+		c.quality = append([]byte{c.quality[0] ^ 1}, c.quality[1:]...)
+		var h *remoteHarvester
+		m.farmer.mutex.RLock()
+		for h = range m.farmer.harvesters {
+		}
+		m.farmer.mutex.RUnlock()
+		m.farmer.handleQuality(h, qualityMessage(miningInfo, c))
+		// the scan may have found more qualities
+		tampered := <-candidates
+		for !bytes.Equal(tampered.quality, c.quality) {
+			tampered = <-candidates
+		}
+		if _, err := tampered.fetchProof(ctx); err == nil {
+			t.Fatal("proof of a tampered quality accepted")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no candidate from the harvester")
+	}
+}
+
+// TestFarmerKeyAudit the farmer audits the farmer keys of the harvester plots
+// and drops their qualities when the key is missing and excluded
+func TestFarmerKeyAudit(t *testing.T) {
+	f, err := chiapos2.Open(testPlotPath + "/k14.plot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fPubKey, _ := f.GetFarmerPublicKey()
+
+	farmerCfg := &config.Config{MissingFarmerKey: "exclude", FarmerKey: make(map[string]string)}
+	m, candidates := startLoopback(t, farmerCfg)
+	keys := m.AuditKeys()
+	if len(keys) != 1 || keys[0].Harvester != "loopback" || keys[0].FarmerPublicKey != fPubKey ||
+		keys[0].Plots != 1 || keys[0].Excluded != 1 || keys[0].Configured {
+		t.Fatalf("audit %+v", keys[0])
+	}
+
+	miningInfo := &entity.MiningInfo{Height: 1, Challenge: testChallenge(t, 1), Difficulty: 1, ScanIterations: 1}
+	m.mutex.Lock()
+	m.miningInfo = miningInfo
+	m.scanCtx, m.cancelScan = context.WithCancel(context.Background())
+	m.mutex.Unlock()
+	defer m.cancelScan()
+	m.farmer.broadcastMessage(challengeMessage(miningInfo))
+	select {
+	case c := <-candidates:
+		t.Fatalf("candidate of an excluded plot %v", c.plotFile)
+	case <-time.After(time.Second):
+	}
+
+	m, _ = startLoopback(t, &config.Config{MissingFarmerKey: "exclude", FarmerKey: map[string]string{fPubKey: "configured"}})
+	if keys := m.AuditKeys(); !keys[0].Configured || keys[0].Excluded != 0 {
+		t.Fatalf("audit %+v", keys[0])
+	}
+}
@@ -0,0 +1,140 @@
+package miner
+
+import (
+	"chia-miner/pkg/config"
+	"chia-miner/pkg/metrics"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDiskWorkers = 2
+	defaultMaxWorkers  = 16
+)
+
+// DeviceScanStatus time spent on the plots of one device
+type DeviceScanStatus struct {
+	Device   string  `json:"device"`
+	Plots    int     `json:"plots"`
+	Duration float64 `json:"duration"` // seconds
+}
+
+// scheduler bounds the concurrent plot lookups per device and in total,
+// it is shared by all spaces so plot directories on the same disk share the limit
+type scheduler struct {
+	diskWorkers int
+	global      chan struct{}
+	mutex       sync.Mutex
+	devices     map[string]chan struct{}
+}
+
+func newScheduler(cfg *config.Config) *scheduler {
+	diskWorkers := cfg.Scan.DiskWorkers
+	if diskWorkers <= 0 {
+		diskWorkers = defaultDiskWorkers
+	}
+	maxWorkers := cfg.Scan.MaxWorkers
+	if maxWorkers <= 0 {
+		maxWorkers = defaultMaxWorkers
+	}
+	return &scheduler{
+		diskWorkers: diskWorkers,
+		global:      make(chan struct{}, maxWorkers),
+		devices:     make(map[string]chan struct{}),
+	}
+}
+
+func (sc *scheduler) deviceSlots(device string) chan struct{} {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	slots, ok := sc.devices[device]
+	if !ok {
+		slots = make(chan struct{}, sc.diskWorkers)
+		sc.devices[device] = slots
+	}
+	return slots
+}
+
+// run calls fn for every plot and waits for all of them, the plots are grouped
+// by device and every device is processed independently. Once ctx is done the
+// remaining plots are skipped.
+func (sc *scheduler) run(ctx context.Context, plots []*plot, fn func(p *plot)) []*DeviceScanStatus {
+	groups := make(map[string][]*plot)
+	var devices []string
+	for _, p := range plots {
+		if _, ok := groups[p.device]; !ok {
+			devices = append(devices, p.device)
+		}
+		groups[p.device] = append(groups[p.device], p)
+	}
+
+	list := make([]*DeviceScanStatus, len(devices))
+	var wg sync.WaitGroup
+	for i, device := range devices {
+		wg.Add(1)
+		go func(i int, device string) {
+			defer wg.Done()
+			startTime := time.Now()
+			sc.runDevice(ctx, device, groups[device], fn)
+			list[i] = &DeviceScanStatus{
+				Device:   device,
+				Plots:    len(groups[device]),
+				Duration: time.Since(startTime).Seconds(),
+			}
+			metrics.DeviceScanSeconds.WithLabelValues(device).Observe(list[i].Duration)
+		}(i, device)
+	}
+	wg.Wait()
+	return list
+}
+
+func (sc *scheduler) runDevice(ctx context.Context, device string, plots []*plot, fn func(p *plot)) {
+	slots := sc.deviceSlots(device)
+	workers := sc.diskWorkers
+	if workers > len(plots) {
+		workers = len(plots)
+	}
+
+	ch := make(chan *plot)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range ch {
+				if !sc.acquire(ctx, slots) {
+					continue
+				}
+				if sc.acquire(ctx, sc.global) {
+					fn(p)
+					<-sc.global
+				}
+				<-slots
+			}
+		}()
+	}
+	for _, p := range plots {
+		if ctx.Err() != nil {
+			break
+		}
+		ch <- p
+	}
+	close(ch)
+	wg.Wait()
+}
+
+// acquire takes a slot, returns false if ctx is done first
+func (sc *scheduler) acquire(ctx context.Context, slots chan struct{}) bool {
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+	// both cases may be ready at the same time
+	if ctx.Err() != nil {
+		<-slots
+		return false
+	}
+	return true
+}
@@ -4,117 +4,358 @@ import (
 	entity2 "chia-miner/miner/entity"
 	chiapos2 "chia-miner/pkg/chiapos"
 	"chia-miner/pkg/config"
+	"chia-miner/pkg/metrics"
 	"chia-miner/utils"
+	"context"
 	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
 	"github.com/sirupsen/logrus"
+	"os"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
-const targetDeadline = int64(180)
+const (
+	targetDeadline = int64(180)
+	scanTimeout    = 150 * time.Second
+)
+
+// scanRequest a challenge to scan, ctx is cancelled when the challenge is superseded
+type scanRequest struct {
+	ctx        context.Context
+	miningInfo *entity2.MiningInfo
+}
 
 type Space struct {
-	filepath string
-	queue    *utils.Queue
-	files    []*chiapos2.File
-	cfg      *config.Config
+	filepath   string
+	queue      *utils.Queue
+	files      atomic.Value // []*plot
+	loaded     atomic.Value // []*plot, files and duplicates of plots farmed from another file
+	duplicates atomic.Value // []*DuplicatePlot
+	excluded   atomic.Value // []*plot, loaded but not farmed
+	skipped    atomic.Value // []*SkippedPlot, failed to load
+	failed     map[string]*failedPlot
+	cfg        *config.Config
+	sched      *scheduler
+	index      *plotIndex
+	handle     candidateHandler
+
+	reloadMutex sync.Mutex
+	lastScan    atomic.Value // *ScanStatus
 }
 
-func NewSpace(filepath string, cfg *config.Config) *Space {
+func NewSpace(filepath string, cfg *config.Config, sched *scheduler, index *plotIndex) *Space {
 	space := &Space{
 		filepath: filepath,
+		failed:   make(map[string]*failedPlot),
 		cfg:      cfg,
+		sched:    sched,
+		index:    index,
+		handle:   GetMiner().handleCandidate,
 	}
+	space.files.Store([]*plot{})
+	space.loaded.Store([]*plot{})
+	space.duplicates.Store([]*DuplicatePlot{})
+	space.excluded.Store([]*plot{})
+	space.skipped.Store([]*SkippedPlot{})
 	space.queue = utils.NewQueue(1024, space.run)
-	files := utils.GetFileList(filepath, ".plot")
-	for _, fileInfo := range files {
+	index.add(space)
+	space.reload()
+	return space
+}
+
+// getFiles returns the current plot set, the returned slice must not be modified
+func (s *Space) getFiles() []*plot {
+	return s.files.Load().([]*plot)
+}
+
+// getLoaded returns the farmable plots, duplicates included
+func (s *Space) getLoaded() []*plot {
+	return s.loaded.Load().([]*plot)
+}
+
+// getDuplicates returns the plots that are farmed from another file
+func (s *Space) getDuplicates() []*DuplicatePlot {
+	return s.duplicates.Load().([]*DuplicatePlot)
+}
+
+// getExcluded returns the plots that are loaded but not farmed
+func (s *Space) getExcluded() []*plot {
+	return s.excluded.Load().([]*plot)
+}
+
+// getSkipped returns the plots that failed to load and why
+func (s *Space) getSkipped() []*SkippedPlot {
+	return s.skipped.Load().([]*SkippedPlot)
+}
+
+// failedPlot a plot that failed to load, it is retried once modified
+type failedPlot struct {
+	modTime time.Time
+	skipped *SkippedPlot
+}
+
+// excludePlot reports whether the plot must not be farmed
+func (s *Space) excludePlot(f *plot) bool {
+	if !s.cfg.ExcludeMissingFarmerKey() {
+		return false
+	}
+	fPubKey, _ := f.GetFarmerPublicKey()
+	_, ok := s.cfg.FarmerKey[fPubKey]
+	return !ok
+}
+
+// reload rescans the plot directory, opens newly added plots and drops plots
+// that were deleted or became unreadable. The new set is swapped in atomically,
+// so a running scan keeps working on the set it started with.
+func (s *Space) reload() {
+	s.reloadMutex.Lock()
+	defer s.reloadMutex.Unlock()
+
+	current := make(map[string]*plot)
+	for _, f := range s.getLoaded() {
+		current[f.GetFilename()] = f
+	}
+	for _, f := range s.getExcluded() {
+		current[f.GetFilename()] = f
+	}
+
+	files := make([]*plot, 0, len(current))
+	excluded := make([]*plot, 0)
+	skipped := make([]*SkippedPlot, 0)
+	listed := make(map[string]bool)
+	added := 0
+	for _, fileInfo := range utils.GetFileList(s.filepath, ".plot") {
+		listed[fileInfo.FilePath] = true
+		if f, ok := current[fileInfo.FilePath]; ok {
+			delete(current, fileInfo.FilePath)
+			if err := utils.CheckFileReadable(fileInfo.FilePath); err != nil {
+				logrus.Errorf("Plot is not readable, drop it %v %v", fileInfo.FilePath, err)
+				continue
+			}
+			if s.excludePlot(f) {
+				excluded = append(excluded, f)
+			} else {
+				files = append(files, f)
+			}
+			continue
+		}
+
+		stat, err := os.Stat(fileInfo.FilePath)
+		if err != nil {
+			continue
+		}
+		// skip plots that failed before and have not changed since
+		if failed, ok := s.failed[fileInfo.FilePath]; ok && failed.modTime.Equal(stat.ModTime()) {
+			skipped = append(skipped, failed.skipped)
+			continue
+		}
 		f, err := chiapos2.Open(fileInfo.FilePath)
 		if err != nil {
-			logrus.Errorf("Failed to load, error %v %v", fileInfo.FilePath, err)
+			skip := &SkippedPlot{File: fileInfo.FilePath, Reason: chiapos2.SkipReason(err), Error: err.Error()}
+			if format, err := chiapos2.ReadPlotFormat(fileInfo.FilePath); err == nil {
+				skip.Format = format.String()
+			}
+			logrus.Errorf("Skip plot %v, %v: %v", fileInfo.FilePath, skip.Reason, err)
+			s.failed[fileInfo.FilePath] = &failedPlot{modTime: stat.ModTime(), skipped: skip}
+			skipped = append(skipped, skip)
 			continue
 		}
-		logrus.Debugf("Load chia file %v", fileInfo.FilePath)
-		space.files = append(space.files, f)
+		delete(s.failed, fileInfo.FilePath)
+		device, err := utils.GetDeviceId(fileInfo.FilePath)
+		if err != nil {
+			logrus.Warnf("Failed to get device of %v %v", fileInfo.FilePath, err)
+		}
+		logrus.Debugf("Load chia file %v format %v", fileInfo.FilePath, f.GetFormat())
+		p := &plot{File: f, device: device}
+		if s.excludePlot(p) {
+			fPubKey, _ := p.GetFarmerPublicKey()
+			logrus.Warnf("Exclude plot %v, farmer private key is not configured, farmer public key %v", fileInfo.FilePath, fPubKey)
+			excluded = append(excluded, p)
+			continue
+		}
+		files = append(files, p)
+		added++
+	}
+	// the remaining plots were deleted
+	for filename := range current {
+		logrus.Infof("Plot removed %v", filename)
+	}
+	for filename := range s.failed {
+		if !listed[filename] {
+			delete(s.failed, filename)
+		}
+	}
+
+	s.loaded.Store(files)
+	s.excluded.Store(excluded)
+	s.skipped.Store(skipped)
+	s.index.resolve()
+	if added > 0 || len(current) > 0 {
+		logrus.Infof("Plots reloaded %v: %v plots, %v added, %v removed", s.filepath, len(files), added, len(current))
 	}
-	return space
 }
 
-func (s *Space) requestScan(miningInfo *entity2.MiningInfo) {
-	s.queue.Push(miningInfo)
+// Status returns the plot count, capacity and last scan statistics
+func (s *Space) Status() *SpaceStatus {
+	files := s.getFiles()
+	status := &SpaceStatus{
+		Path:       s.filepath,
+		Plots:      len(files),
+		Formats:    make(map[string]int),
+		Skipped:    s.getSkipped(),
+		Duplicates: s.getDuplicates(),
+	}
+	for _, f := range files {
+		status.Capacity += chiapos2.PlotFileSize(f.GetSize())
+		status.RawSpace += chiapos2.ExpectedPlotSize(f.GetSize())
+		status.Formats[f.GetFormat().String()]++
+	}
+	if lastScan, ok := s.lastScan.Load().(*ScanStatus); ok {
+		status.LastScan = lastScan
+	}
+	return status
+}
+
+func (s *Space) requestScan(ctx context.Context, miningInfo *entity2.MiningInfo) {
+	s.queue.Push(&scanRequest{ctx: ctx, miningInfo: miningInfo})
 }
 
 func (s *Space) run(v interface{}) {
-	miningInfo := v.(*entity2.MiningInfo)
-	utils.RunTimeout(func() {
-		s.scan(miningInfo, miningInfo.ScanIterations)
-	}, 150*1000)
+	request := v.(*scanRequest)
+	miningInfo := request.miningInfo
+	if request.ctx.Err() != nil {
+		logrus.Debugf("Drop obsolete scan %v height %v scanIterations %v", s.filepath, miningInfo.Height, miningInfo.ScanIterations)
+		return
+	}
+	ctx, cancel := context.WithTimeout(request.ctx, scanTimeout)
+	defer cancel()
+	s.scan(ctx, request)
 }
 func (s *Space) checkFilter(filterBits int, filterData []byte) bool {
 	filterValue := binary.LittleEndian.Uint32(filterData)
 	data := filterValue << (32 - filterBits)
 	return data == 0
 }
-func (s *Space) scan(miningInfo *entity2.MiningInfo, scanIterations int64) {
-	for _, f := range s.files {
-		var b8 [8]byte
-		binary.BigEndian.PutUint64(b8[:], uint64(scanIterations))
-		challengeBytes := s.sha256s(miningInfo.Challenge, b8[:])
+func (s *Space) scan(ctx context.Context, request *scanRequest) {
+	miningInfo := request.miningInfo
+	scanIterations := miningInfo.ScanIterations
+	startTime := time.Now()
+	status := &ScanStatus{
+		Height:         miningInfo.Height,
+		Challenge:      hex.EncodeToString(miningInfo.Challenge),
+		ScanIterations: scanIterations,
+		StartTime:      startTime.Unix(),
+	}
+	defer func() {
+		status.Duration = time.Since(startTime).Seconds()
+		status.Cancelled = ctx.Err() != nil
+		s.lastScan.Store(status)
+		metrics.PlotsChecked.WithLabelValues(s.filepath).Add(float64(status.PlotsChecked))
+		metrics.PlotsPassed.WithLabelValues(s.filepath).Add(float64(status.PlotsPassed))
+		metrics.QualitiesFound.WithLabelValues(s.filepath).Add(float64(status.Qualities))
+		metrics.ProofsFetched.WithLabelValues(s.filepath).Add(float64(status.Proofs))
+		if status.Cancelled {
+			logrus.Infof("Scan %v height %v cancelled after %.3fs: %v", s.filepath, miningInfo.Height, status.Duration, ctx.Err())
+		}
+		logrus.Debugf("Scan %v finished in %.3fs: %v plots, %v passed filter, %v qualities, %v proofs",
+			s.filepath, status.Duration, status.PlotsChecked, status.PlotsPassed, status.Qualities, status.Proofs)
+		for _, device := range status.Devices {
+			logrus.Debugf("Scan %v device %v: %v plots in %.3fs", s.filepath, device.Device, device.Plots, device.Duration)
+		}
+	}()
 
+	challengeBytes := plotChallenge(miningInfo.Challenge, scanIterations)
+
+	files := s.getFiles()
+	passed := make([]*plot, 0)
+	for _, f := range files {
 		chHash := s.sha256s(f.GetId(), challengeBytes)
-		if !s.checkFilter(miningInfo.FilterBits, chHash) {
-			continue
+		if s.checkFilter(miningInfo.FilterBits, chHash) {
+			passed = append(passed, f)
 		}
+	}
+	status.PlotsChecked = len(files)
+	status.PlotsPassed = len(passed)
 
-		arrQualities, _ := f.GetQualitiesForChallenge(challengeBytes)
-
-		for i, qualities := range arrQualities {
-			requiredIters := chiapos2.CalculateIterationsQuality(qualities, int32(f.GetSize()), miningInfo.Difficulty, challengeBytes)
-			inflate := 80 * 512 / (1 << miningInfo.FilterBits)
-			SubDeadline := (requiredIters * uint64(inflate)) / 24433591728
-
-			if int64(SubDeadline) < targetDeadline {
-				proof, ok := f.GetFullProof(challengeBytes, i)
-				if !ok {
-					logrus.Error("Failed to read proof")
-					continue
-				}
-				fPubKey, err := f.GetFarmerPublicKey()
-				if err != nil {
-					continue
-				}
-				privateKey, ok := s.cfg.FarmerKey[fPubKey]
-				if !ok {
-					logrus.Errorf("Chia farmer private key is not configured, farmer public key %v", fPubKey)
-					continue
-				}
-
-				if requiredIters > atomic.LoadUint64(&miningInfo.BestQuality) {
-					continue
-				}
-				atomic.StoreUint64(&miningInfo.BestQuality, requiredIters)
-
-				submitProof := &entity2.SubmitProof{
-					//Quality:         requiredIters,
-					Height:           miningInfo.Height,
-					ScanIterations:   miningInfo.ScanIterations,
-					Challenge:        hex.EncodeToString(miningInfo.Challenge),
-					QualityString:    hex.EncodeToString(qualities),
-					PlotSize:         f.GetSize(),
-					PlotId:           hex.EncodeToString(f.GetId()),
-					PoolPublicKey:    hex.EncodeToString(f.GetPoolPublicKeyBinary()),
-					FarmerPublicKey:  hex.EncodeToString(f.GetFarmerPublicKeyBinary()),
-					FarmerPrivateKey: privateKey,
-					SecurityKey:      hex.EncodeToString(f.GetSecurityKeyBinary()),
-					ResponseNumber:   int32(i),
-					ProofXs:          hex.EncodeToString(proof),
-					RequiredIters:    requiredIters,
-				}
-				GetJsonRpc().Submit(submitProof)
-			}
+	var mutex sync.Mutex
+	status.Devices = s.sched.run(ctx, passed, func(f *plot) {
+		qualities, proofs := s.lookup(ctx, request, challengeBytes, f)
+		mutex.Lock()
+		status.Qualities += qualities
+		status.Proofs += proofs
+		mutex.Unlock()
+	})
+}
+
+// lookup looks up the qualities of a plot passing the filter and hands them to
+// the candidate handler, returns the number of qualities and proofs
+func (s *Space) lookup(ctx context.Context, request *scanRequest, challengeBytes []byte, f *plot) (int, int) {
+	lookupTime := time.Now()
+	arrQualities, _ := f.GetQualitiesForChallenge(challengeBytes)
+	metrics.QualityLookupSeconds.WithLabelValues(s.filepath).Observe(time.Since(lookupTime).Seconds())
+
+	proofs := 0
+	for i, qualities := range arrQualities {
+		if ctx.Err() != nil {
+			break
+		}
+		index := i
+		localPk, plotPk, err := f.publicKeys()
+		if err != nil {
+			logrus.Errorf("Failed to derive the public keys of plot %v %v", f.GetFilename(), err)
+			break
+		}
+		c := &candidate{
+			plotId:         f.GetId(),
+			plotFile:       f.GetFilename(),
+			size:           f.GetSize(),
+			memo:           f.GetPlotMemo(),
+			localPublicKey: localPk,
+			plotPublicKey:  plotPk,
+			index:          index,
+			quality:        qualities,
+			fetchProof: func(ctx context.Context) ([]byte, error) {
+				proofTime := time.Now()
+				proof, err := f.fullProof(challengeBytes, index, qualities)
+				metrics.FullProofSeconds.WithLabelValues(s.filepath).Observe(time.Since(proofTime).Seconds())
+				return proof, err
+			},
+			submitCtx: request.ctx,
+		}
+		if s.handle(ctx, request.miningInfo, challengeBytes, c) {
+			proofs++
 		}
 	}
+	return len(arrQualities), proofs
 }
+
+// updateBestQuality stores requiredIters as the best quality of the challenge,
+// returns false if a better one was already found
+func updateBestQuality(miningInfo *entity2.MiningInfo, requiredIters uint64) bool {
+	for {
+		best := atomic.LoadUint64(&miningInfo.BestQuality)
+		if requiredIters > best {
+			return false
+		}
+		if atomic.CompareAndSwapUint64(&miningInfo.BestQuality, best, requiredIters) {
+			return true
+		}
+	}
+}
+
+// plotChallenge returns the challenge the plots are looked up with, sha256(challenge || scan iterations)
+func plotChallenge(challenge []byte, scanIterations int64) []byte {
+	var b8 [8]byte
+	binary.BigEndian.PutUint64(b8[:], uint64(scanIterations))
+	hash := sha256.New()
+	hash.Write(challenge)
+	hash.Write(b8[:])
+	return hash.Sum(nil)
+}
+
 func (s *Space) sha256(data []byte) []byte {
 	hash := sha256.New()
 	hash.Write(data)
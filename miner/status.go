@@ -0,0 +1,85 @@
+package miner
+
+import (
+	"chia-miner/miner/entity"
+	"github.com/pkg/errors"
+	"sync"
+)
+
+const maxSubmitRecords = 100
+
+// ScanStatus statistics of one scan of a space
+type ScanStatus struct {
+	Height         uint32  `json:"height"`
+	Challenge      string  `json:"challenge"`
+	ScanIterations int64   `json:"scan_iterations"`
+	StartTime      int64   `json:"start_time"`
+	Duration       float64 `json:"duration"` // seconds
+	PlotsChecked   int     `json:"plots_checked"`
+	PlotsPassed    int     `json:"plots_passed"`
+	Qualities      int     `json:"qualities"`
+	Proofs         int     `json:"proofs"`
+	Cancelled      bool    `json:"cancelled"`
+
+	Devices []*DeviceScanStatus `json:"devices"`
+}
+
+// SpaceStatus status of a plot directory
+type SpaceStatus struct {
+	Path       string           `json:"path"`
+	Plots      int              `json:"plots"`
+	Capacity   uint64           `json:"capacity"`  // bytes on disk
+	RawSpace   uint64           `json:"raw_space"` // bytes, expected plot size of the k sizes
+	Formats    map[string]int   `json:"formats"`   // plots per format, v1, v2 or v2 C<level>
+	Skipped    []*SkippedPlot   `json:"skipped"`
+	Duplicates []*DuplicatePlot `json:"duplicates"` // not farmed, the plot is farmed from another file
+	LastScan   *ScanStatus      `json:"last_scan"`
+}
+
+// SkippedPlot a plot file that is not farmed because it failed to load, Reason is
+// one of not_plot, unsupported_version, compressed, corrupted or unreadable
+type SkippedPlot struct {
+	File   string `json:"file"`
+	Format string `json:"format,omitempty"`
+	Reason string `json:"reason"`
+	Error  string `json:"error"`
+}
+
+// SubmitRecord a submitted proof and the response of the node
+type SubmitRecord struct {
+	Time     int64                `json:"time"`
+	Proof    *entity.SubmitProof  `json:"proof"`
+	Attempts int                  `json:"attempts"`
+	Result   *entity.SubmitResult `json:"result,omitempty"`
+	Response string               `json:"response,omitempty"`
+	Error    string               `json:"error,omitempty"`
+	Code     int                  `json:"code,omitempty"` // RawRpcError code
+}
+
+// submitHistory keeps the most recent submissions
+type submitHistory struct {
+	mutex   sync.Mutex
+	records []*SubmitRecord
+}
+
+func (h *submitHistory) add(record *SubmitRecord, err error) {
+	if err != nil {
+		record.Error = err.Error()
+		if rpcErr, ok := errors.Cause(err).(RawRpcError); ok {
+			record.Code = rpcErr.Code()
+		}
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.records = append(h.records, record)
+	if len(h.records) > maxSubmitRecords {
+		h.records = h.records[len(h.records)-maxSubmitRecords:]
+	}
+}
+
+func (h *submitHistory) list() []*SubmitRecord {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append([]*SubmitRecord{}, h.records...)
+}
@@ -0,0 +1,192 @@
+package miner
+
+import (
+	"chia-miner/miner/entity"
+	"chia-miner/pkg/history"
+	"chia-miner/pkg/metrics"
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	submitRetryMinDelay = time.Second
+	submitRetryMaxDelay = 16 * time.Second
+	submitDedupExpire   = time.Hour
+)
+
+// SubmitStats counters of the submitted proofs
+type SubmitStats struct {
+	Submitted  int         `json:"submitted"`
+	Accepted   int         `json:"accepted"`
+	Rejected   int         `json:"rejected"`
+	Failed     int         `json:"failed"`
+	Duplicates int         `json:"duplicates"`
+	Retries    int         `json:"retries"`
+	ErrorCodes map[int]int `json:"error_codes"` // RawRpcError code -> count
+}
+
+// submitter submits proofs to the node, retrying until the challenge is superseded
+type submitter struct {
+	mutex     sync.Mutex
+	submitted map[string]time.Time
+	stats     SubmitStats
+	records   submitHistory
+}
+
+func newSubmitter() *submitter {
+	return &submitter{
+		submitted: make(map[string]time.Time),
+		stats:     SubmitStats{ErrorCodes: make(map[int]int)},
+	}
+}
+
+func submitKey(proof *entity.SubmitProof) string {
+	return fmt.Sprintf("%s/%s/%d/%d", proof.PlotId, proof.Challenge, proof.ScanIterations, proof.ResponseNumber)
+}
+
+// markSubmitted records the proof, returns false if it was submitted already
+func (s *submitter) markSubmitted(proof *entity.SubmitProof) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for key, t := range s.submitted {
+		if now.Sub(t) > submitDedupExpire {
+			delete(s.submitted, key)
+		}
+	}
+	key := submitKey(proof)
+	if _, ok := s.submitted[key]; ok {
+		s.stats.Duplicates++
+		metrics.Submissions.WithLabelValues("duplicate").Inc()
+		return false
+	}
+	s.submitted[key] = now
+	s.stats.Submitted++
+	return true
+}
+
+// submit submits the proof in the background, ctx bounds the retries and is
+// cancelled when the challenge of the proof is superseded
+func (s *submitter) submit(ctx context.Context, proof *entity.SubmitProof) {
+	if !s.markSubmitted(proof) {
+		logrus.Debugf("Skip duplicate proof plot %v height %v response %v", proof.PlotId, proof.Height, proof.ResponseNumber)
+		return
+	}
+	go s.run(ctx, proof)
+}
+
+func (s *submitter) run(ctx context.Context, proof *entity.SubmitProof) {
+	delay := submitRetryMinDelay
+	for attempt := 1; ; attempt++ {
+		result, raw, err := GetJsonRpc().Submit(ctx, proof)
+		if err == nil {
+			s.finish(proof, attempt, result, raw, nil)
+			return
+		}
+		if ctx.Err() != nil {
+			s.finish(proof, attempt, nil, raw, errors.Wrap(err, "challenge expired"))
+			return
+		}
+		if !isRetryable(err) {
+			s.finish(proof, attempt, nil, raw, err)
+			return
+		}
+		logrus.Warnf("Submit proof height %v plot %v failed, retry in %v, attempt %v error %v",
+			proof.Height, proof.PlotId, delay, attempt, err)
+		s.mutex.Lock()
+		s.stats.Retries++
+		s.mutex.Unlock()
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			s.finish(proof, attempt, nil, raw, errors.Wrap(err, "challenge expired"))
+			return
+		}
+		delay *= 2
+		if delay > submitRetryMaxDelay {
+			delay = submitRetryMaxDelay
+		}
+	}
+}
+
+func (s *submitter) finish(proof *entity.SubmitProof, attempts int, result *entity.SubmitResult, raw string, err error) {
+	record := &SubmitRecord{
+		Time:     time.Now().Unix(),
+		Proof:    proof,
+		Attempts: attempts,
+		Result:   result,
+		Response: raw,
+	}
+	s.records.add(record, err)
+	submission := &history.Submission{
+		Height:         proof.Height,
+		Challenge:      proof.Challenge,
+		ScanIterations: proof.ScanIterations,
+		PlotId:         proof.PlotId,
+		ResponseNumber: proof.ResponseNumber,
+		RequiredIters:  proof.RequiredIters,
+		Error:          record.Error,
+		Code:           record.Code,
+		Attempts:       attempts,
+	}
+	if result != nil {
+		submission.Accepted = result.Accepted
+		submission.Message = result.Message
+	}
+	GetMiner().history.AddSubmission(submission)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	switch {
+	case err != nil:
+		s.stats.Failed++
+		metrics.Submissions.WithLabelValues("failed").Inc()
+		if rpcErr, ok := errors.Cause(err).(RawRpcError); ok {
+			s.stats.ErrorCodes[rpcErr.Code()]++
+			logrus.Errorf("Submit proof height %v plot %v rejected by node, code %v message %v",
+				proof.Height, proof.PlotId, rpcErr.Code(), rpcErr.Message())
+		} else {
+			logrus.Errorf("Submit proof height %v plot %v failed after %v attempts, error %v",
+				proof.Height, proof.PlotId, attempts, err)
+		}
+	case result.Accepted:
+		s.stats.Accepted++
+		metrics.Submissions.WithLabelValues("accepted").Inc()
+		logrus.Infof("Proof accepted, height %v plot %v required iters %v", proof.Height, proof.PlotId, proof.RequiredIters)
+	default:
+		s.stats.Rejected++
+		metrics.Submissions.WithLabelValues("rejected").Inc()
+		logrus.Warnf("Proof rejected, height %v plot %v message %v", proof.Height, proof.PlotId, result.Message)
+	}
+}
+
+func (s *submitter) getStats() SubmitStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stats := s.stats
+	stats.ErrorCodes = make(map[int]int, len(s.stats.ErrorCodes))
+	for code, count := range s.stats.ErrorCodes {
+		stats.ErrorCodes[code] = count
+	}
+	return stats
+}
+
+// isRetryable reports whether the submission failed for a transient reason,
+// errors returned by the node itself are final
+func isRetryable(err error) bool {
+	switch errors.Cause(err).(type) {
+	case RawRpcError:
+		return false
+	}
+	switch errors.Cause(err) {
+	case ErrUnauthenticated, ErrPermissionDenied:
+		return false
+	}
+	return true
+}
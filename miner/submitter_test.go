@@ -0,0 +1,46 @@
+package miner
+
+import (
+	"chia-miner/miner/entity"
+	"chia-miner/pkg/config"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestSubmitHangingNode a node that never answers does not block the
+// submission beyond the challenge
+func TestSubmitHangingNode(t *testing.T) {
+	release := make(chan struct{})
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer node.Close()
+	defer close(release)
+
+	cfg := &config.Config{}
+	cfg.Rpc.Url = node.URL
+	InitJsonRpc(cfg)
+
+	s := newSubmitter()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		s.run(ctx, &entity.SubmitProof{Height: 1, PlotId: "00"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("submission not cancelled with the challenge")
+	}
+	if stats := s.getStats(); stats.Failed != 1 {
+		t.Fatalf("stats %+v", stats)
+	}
+}
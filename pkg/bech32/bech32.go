@@ -0,0 +1,123 @@
+// Package bech32 encodes addresses with the bech32m checksum (BIP-350)
+package bech32
+
+import (
+	"errors"
+	"strings"
+)
+
+const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32mConst is the checksum constant of bech32m
+const bech32mConst = 0x2bc830a3
+
+var ErrInvalidAddress = errors.New("invalid bech32m address")
+var ErrInvalidChecksum = errors.New("invalid bech32m checksum")
+
+func polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func hrpExpand(hrp string) []byte {
+	ret := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		ret = append(ret, hrp[i]>>5)
+	}
+	ret = append(ret, 0)
+	for i := 0; i < len(hrp); i++ {
+		ret = append(ret, hrp[i]&31)
+	}
+	return ret
+}
+
+func createChecksum(hrp string, data []byte) []byte {
+	values := append(hrpExpand(hrp), data...)
+	values = append(values, 0, 0, 0, 0, 0, 0)
+	mod := polymod(values) ^ bech32mConst
+	checksum := make([]byte, 6)
+	for i := range checksum {
+		checksum[i] = byte(mod>>uint(5*(5-i))) & 31
+	}
+	return checksum
+}
+
+// convertBits regroups bits, from 8 to 5 bits when encoding and back when decoding
+func convertBits(data []byte, from, to uint, pad bool) ([]byte, error) {
+	acc, bits := uint32(0), uint(0)
+	maxv := uint32(1)<<to - 1
+	ret := make([]byte, 0, len(data)*int(from)/int(to)+1)
+	for _, b := range data {
+		if uint32(b)>>from != 0 {
+			return nil, ErrInvalidAddress
+		}
+		acc = acc<<from | uint32(b)
+		bits += from
+		for bits >= to {
+			bits -= to
+			ret = append(ret, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			ret = append(ret, byte(acc<<(to-bits)&maxv))
+		}
+	} else if bits >= from || acc<<(to-bits)&maxv != 0 {
+		return nil, ErrInvalidAddress
+	}
+	return ret, nil
+}
+
+// Encode encodes data, usually a puzzle hash, as a bech32m address with the hrp prefix
+func Encode(hrp string, data []byte) (string, error) {
+	values, err := convertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, v := range append(values, createChecksum(hrp, values)...) {
+		sb.WriteByte(charset[v])
+	}
+	return sb.String(), nil
+}
+
+// Decode returns the prefix and data of a bech32m address
+func Decode(address string) (string, []byte, error) {
+	if strings.ToLower(address) != address && strings.ToUpper(address) != address {
+		return "", nil, ErrInvalidAddress
+	}
+	address = strings.ToLower(address)
+	pos := strings.LastIndexByte(address, '1')
+	if pos < 1 || pos+7 > len(address) {
+		return "", nil, ErrInvalidAddress
+	}
+	hrp := address[:pos]
+	values := make([]byte, 0, len(address)-pos-1)
+	for i := pos + 1; i < len(address); i++ {
+		v := strings.IndexByte(charset, address[i])
+		if v < 0 {
+			return "", nil, ErrInvalidAddress
+		}
+		values = append(values, byte(v))
+	}
+	if polymod(append(hrpExpand(hrp), values...)) != bech32mConst {
+		return "", nil, ErrInvalidChecksum
+	}
+	data, err := convertBits(values[:len(values)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return hrp, data, nil
+}
@@ -0,0 +1,81 @@
+package bls
+
+import (
+	"errors"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// ErrEmptyAggregate is returned when aggregating an empty set of signatures or keys
+var ErrEmptyAggregate = errors.New("nothing to aggregate")
+
+// AggregateSignatures combines signatures into a single signature
+func AggregateSignatures(sigs [][]byte) ([]byte, error) {
+	if len(sigs) == 0 {
+		return nil, ErrEmptyAggregate
+	}
+	g2 := bls12381.NewG2()
+	sum := g2.Zero()
+	for _, sig := range sigs {
+		p, err := decodeG2(sig)
+		if err != nil {
+			return nil, err
+		}
+		g2.Add(sum, sum, p)
+	}
+	return g2.ToCompressed(sum), nil
+}
+
+// AggregatePublicKeys combines public keys into a single public key
+func AggregatePublicKeys(pks []*PublicKey) (*PublicKey, error) {
+	if len(pks) == 0 {
+		return nil, ErrEmptyAggregate
+	}
+	g1 := bls12381.NewG1()
+	sum := g1.Zero()
+	for _, pk := range pks {
+		p, err := decodeG1(pk.data)
+		if err != nil {
+			return nil, err
+		}
+		g1.Add(sum, sum, p)
+	}
+	return &PublicKey{data: g1.ToCompressed(sum)}, nil
+}
+
+// AggregateVerify verifies an aggregate augmented scheme signature, messages[i] signed by pks[i]
+func AggregateVerify(pks []*PublicKey, messages [][]byte, sig []byte) error {
+	if len(pks) != len(messages) {
+		return ErrVerification
+	}
+	s, err := decodeG2(sig)
+	if err != nil {
+		return err
+	}
+
+	engine := bls12381.NewEngine()
+	if len(pks) == 0 {
+		if engine.G2.IsZero(s) {
+			return nil
+		}
+		return ErrVerification
+	}
+	for i, pk := range pks {
+		p, err := decodeG1(pk.data)
+		if err != nil {
+			return err
+		}
+		h, err := augHash(pk.data, messages[i])
+		if err != nil {
+			return err
+		}
+		engine.AddPair(p, h)
+	}
+
+	// prod e(pk_i, H(pk_i || m_i)) == e(g1, sig)
+	engine.AddPairInv(engine.G1.One(), s)
+	if !engine.Check() {
+		return ErrVerification
+	}
+	return nil
+}
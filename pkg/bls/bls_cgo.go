@@ -1,5 +1,5 @@
-//go:build cgo
-// +build cgo
+//go:build cgo && !blspure
+// +build cgo,!blspure
 
 package bls
 
@@ -194,3 +194,36 @@ func (pk *PublicKey) Add(other *PublicKey) *PublicKey {
 	bufLen := C.g1_bytes(pkT, (*C.uint8_t)(unsafe.Pointer(&buf[0])), C.size_t(len(buf)))
 	return &PublicKey{data: buf[0:int(bufLen):int(bufLen)]}
 }
+
+// AggregatePrivateKeys combines private keys, the aggregate signs for the aggregate public key
+func AggregatePrivateKeys(keys []*PrivateKey) (*PrivateKey, error) {
+	if len(keys) == 0 {
+		return nil, ErrEmptyAggregate
+	}
+	keyTs := make([]*C.PrivateKeyT, 0, len(keys))
+	defer func() {
+		for _, keyT := range keyTs {
+			C.key_destroy(keyT)
+		}
+	}()
+	for _, key := range keys {
+		if err := checkPrivateKeyData(key.data); err != nil {
+			return nil, err
+		}
+		keyT := C.key_from_bytes((*C.uint8_t)(unsafe.Pointer(&key.data[0])), C.size_t(len(key.data)))
+		if keyT == nil {
+			return nil, ErrInvalidKey
+		}
+		keyTs = append(keyTs, keyT)
+	}
+
+	aggT := C.key_aggregate((**C.PrivateKeyT)(unsafe.Pointer(&keyTs[0])), C.size_t(len(keyTs)))
+	if aggT == nil {
+		return nil, ErrInvalidKey
+	}
+	defer C.key_destroy(aggT)
+
+	buf := make([]byte, 32)
+	bufLen := C.key_bytes(aggT, (*C.uint8_t)(unsafe.Pointer(&buf[0])), C.size_t(len(buf)))
+	return &PrivateKey{data: buf[0:int(bufLen):int(bufLen)]}, nil
+}
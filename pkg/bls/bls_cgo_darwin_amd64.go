@@ -1,3 +1,6 @@
+//go:build !blspure
+// +build !blspure
+
 package bls
 
 // #cgo LDFLAGS: -lblsc_x86_64-apple-darwin20 -lc++
@@ -1,3 +1,6 @@
+//go:build !blspure
+// +build !blspure
+
 package bls
 
 // #cgo LDFLAGS: -lblsc_arm-linux-gnueabihf -lstdc++ -static
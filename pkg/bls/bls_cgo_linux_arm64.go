@@ -1,3 +1,6 @@
+//go:build !blspure
+// +build !blspure
+
 package bls
 
 // #cgo LDFLAGS: -lblsc_aarch64-linux-gnu -lstdc++ -static
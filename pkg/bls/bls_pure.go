@@ -0,0 +1,192 @@
+//go:build !cgo || blspure
+// +build !cgo blspure
+
+package bls
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"math/big"
+
+	bls12381 "github.com/kilic/bls12-381"
+	"golang.org/x/crypto/hkdf"
+)
+
+// keyGenSalt is the salt of the EIP-2333 KeyGen, used unhashed like chia does
+var keyGenSalt = []byte("BLS-SIG-KEYGEN-SALT-")
+
+// keyGen derives a private key scalar from a seed
+func keyGen(seed []byte) *big.Int {
+	ikm := make([]byte, 0, len(seed)+1)
+	ikm = append(ikm, seed...)
+	ikm = append(ikm, 0)
+	okm := make([]byte, 48)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, keyGenSalt, []byte{0, 48}), okm); err != nil {
+		panic(err)
+	}
+	return new(big.Int).Mod(new(big.Int).SetBytes(okm), groupOrder)
+}
+
+// lamportSk expands ikm into 255 lamport secret chunks of 32 bytes
+func lamportSk(ikm []byte, salt []byte) []byte {
+	okm := make([]byte, 32*255)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, salt, nil), okm); err != nil {
+		panic(err)
+	}
+	return okm
+}
+
+// parentSkToLamportPk compresses the lamport public key of a parent key (EIP-2333)
+func parentSkToLamportPk(parent []byte, index uint32) []byte {
+	salt := make([]byte, 4)
+	binary.BigEndian.PutUint32(salt, index)
+	notIkm := make([]byte, len(parent))
+	for i, b := range parent {
+		notIkm[i] = b ^ 0xff
+	}
+
+	lamportPk := make([]byte, 0, 2*255*32)
+	for _, sk := range [][]byte{lamportSk(parent, salt), lamportSk(notIkm, salt)} {
+		for i := 0; i < 255; i++ {
+			chunk := sha256.Sum256(sk[i*32 : (i+1)*32])
+			lamportPk = append(lamportPk, chunk[:]...)
+		}
+	}
+	compressed := sha256.Sum256(lamportPk)
+	return compressed[:]
+}
+
+// GenerateKeyFromSeed creates a new BLS signing key.
+func GenerateKeyFromSeed(seed []byte) (*PrivateKey, error) {
+	if len(seed) < 32 {
+		return nil, ErrLimitSeedSize
+	}
+	return &PrivateKey{data: scalarBytes(keyGen(seed))}, nil
+}
+
+// Public returns the public key corresponding to priv.
+func (priv *PrivateKey) Public() crypto.PublicKey {
+	g1 := bls12381.NewG1()
+	p := g1.MulScalarBig(g1.New(), g1.One(), new(big.Int).SetBytes(priv.data))
+	return &PublicKey{data: g1.ToCompressed(p)}
+}
+
+// Sign message by private key.
+// This method implements crypto.Signer.
+// Ignore io.Reader and crypto.SignerOpts
+func (priv *PrivateKey) Sign(_ io.Reader, message []byte, _ crypto.SignerOpts) ([]byte, error) {
+	return priv.SignMessage(message)
+}
+
+// SignMessage message by private key.
+func (priv *PrivateKey) SignMessage(message []byte) ([]byte, error) {
+	return priv.SignMessageWithPrependPublicKey(message, priv.Public().(*PublicKey))
+}
+
+// SignMessageWithPrependPublicKey message by private key and prepend public key.
+func (priv *PrivateKey) SignMessageWithPrependPublicKey(message []byte, prependPublicKey *PublicKey) ([]byte, error) {
+	sk, err := decodeScalar(priv.data)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decodeG1(prependPublicKey.data); err != nil {
+		return nil, err
+	}
+	h, err := augHash(prependPublicKey.data, message)
+	if err != nil {
+		return nil, err
+	}
+	g2 := bls12381.NewG2()
+	return g2.ToCompressed(g2.MulScalarBig(g2.New(), h, sk)), nil
+}
+
+// Decrypt decrypts ciphertext with priv.
+func (priv *PrivateKey) Decrypt(_ io.Reader, ciphertext []byte, _ crypto.DecrypterOpts) (plaintext []byte, err error) {
+	return nil, ErrNotSupport
+}
+
+// DeriveChild derive child private key
+func (priv *PrivateKey) DeriveChild(paths []int) *PrivateKey {
+	data := priv.data[:len(priv.data):len(priv.data)]
+	for _, path := range paths {
+		data = scalarBytes(keyGen(parentSkToLamportPk(data, uint32(path))))
+	}
+	return &PrivateKey{data: data}
+}
+
+// UnmarshalBinary to private key
+// This method implements encoding.BinaryUnmarshaler.
+func (priv *PrivateKey) UnmarshalBinary(data []byte) error {
+	if _, err := decodeScalar(data); err != nil {
+		return err
+	}
+	priv.data = data[0:len(data):len(data)]
+	return nil
+}
+
+// UnmarshalBinary to public key
+// This method implements encoding.BinaryUnmarshaler.
+func (pk *PublicKey) UnmarshalBinary(data []byte) error {
+	if _, err := decodeG1(data); err != nil {
+		return err
+	}
+	pk.data = data[0:len(data):len(data)]
+	return nil
+}
+
+// Verify verifies a BLS signature.
+func (pk *PublicKey) Verify(digest []byte, sig []byte) error {
+	p, err := decodeG1(pk.data)
+	if err != nil {
+		return ErrInvalidKey
+	}
+	s, err := decodeG2(sig)
+	if err != nil {
+		return err
+	}
+	h, err := augHash(pk.data, digest)
+	if err != nil {
+		return err
+	}
+
+	// e(pk, H(pk || m)) == e(g1, sig)
+	engine := bls12381.NewEngine()
+	engine.AddPair(p, h)
+	engine.AddPairInv(engine.G1.One(), s)
+	if !engine.Check() {
+		return ErrVerification
+	}
+	return nil
+}
+
+// Add combine 2 public keys
+func (pk *PublicKey) Add(other *PublicKey) *PublicKey {
+	g1 := bls12381.NewG1()
+	p1, err := g1.FromCompressed(pk.data)
+	if err != nil {
+		panic(ErrInvalidKey)
+	}
+	p2, err := g1.FromCompressed(other.data)
+	if err != nil {
+		panic(ErrInvalidKey)
+	}
+	return &PublicKey{data: g1.ToCompressed(g1.Add(g1.New(), p1, p2))}
+}
+
+// AggregatePrivateKeys combines private keys, the aggregate signs for the aggregate public key
+func AggregatePrivateKeys(keys []*PrivateKey) (*PrivateKey, error) {
+	if len(keys) == 0 {
+		return nil, ErrEmptyAggregate
+	}
+	sum := new(big.Int)
+	for _, key := range keys {
+		sk, err := decodeScalar(key.data)
+		if err != nil {
+			return nil, err
+		}
+		sum.Add(sum, sk)
+	}
+	return &PrivateKey{data: scalarBytes(sum.Mod(sum, groupOrder))}, nil
+}
@@ -0,0 +1,163 @@
+package bls
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// The vectors were produced by the cgo backend (chia bls-signatures). The test
+// has no build tag, run it with and without -tags blspure to check both
+// backends produce the same bytes.
+var keyVectors = []struct {
+	seed        string
+	sk          string
+	pk          string
+	fingerprint uint32
+	child       string // DeriveChild 12381/8444/2/0
+	unhardened  string // DeriveChildUnhardened 12381/8444/2/0
+	sig         string // SignMessage(testMessage)
+}{
+	{
+		seed:        "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		sk:          "4a18022aa9097511134fcf6c024da289058c76d14de712ba264e50e306b6d6e3",
+		pk:          "8f336467f057b373bb3c43815a10ec131119d1bf50c14fa3f9ad86c0ec074f920f936a5315a8365a37fee0afa34c32c6",
+		fingerprint: 1016162455,
+		child:       "264ab71689a7fc891a2447495335a9a24e9106f4867fbafac47c92da3405ee70",
+		unhardened:  "646121b8f1240b34f7c4d72d029159584b7e4a006912a9ff8aca7f2fedb81182",
+		sig:         "b9a33ac15b1da900821f085972f2c3540252b6c7952d58068e387bd33a2967a7df572b21a93d9ca20f7ff9227c63b65518158ce48538bd690badf474b1bc5d2804a1a7d68647b39bae58797b5ad10740a42ffeacab415a6469b21992321ee4cb",
+	},
+	{
+		seed:        "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		sk:          "0befcabff4a664461cc8f190cdd51c05621eb2837c71a1362df5b465a674ecfb",
+		pk:          "820b1d9bcdc0036ac0fd3b983320df94bb39c6102a769852a3181394e762cc33500ea262a71d3ced9879728e9dad81af",
+		fingerprint: 39615654,
+		child:       "06257a34dfaff46115ff81a3aacc5902ae8912dadffccd490a1cb286fb58aa15",
+		unhardened:  "33d414e42d478ba47478c162124f6530024b2694bea64c8b4b75411578c42559",
+		sig:         "b17830bcc7e4348deb394de44daa23a1ac83d354b3f34ff9549a614fa5b6a7ba10d825b09c8213c91988b61c1f8d5d2e1567a7fbbf716bbddbd4649eeaf30ddf530afdf9099a22ebb87312f1da52fa28782b9e9b51153a88f797219f4f7113a1",
+	},
+}
+
+var testMessage = []byte("chia-miner bls test message")
+
+// prependVector first key signing testMessage with the public key of the second prepended
+const prependVector = "90383273a2c2076bafacff7d1eed2af605e370296c9d0f5f12609b7dc28a8840059124bcb12983208f6a7da7a802ac45096c043c2157986903a720f7d53d82eb1dce50535660d21513bafcf3013c6d05315c48f40c4a31956b5e51033603c102"
+
+// aggregateVector aggregate of the signatures of keyVectors
+const aggregateVector = "908c8b872d31767c4cfdcab2b6cfbf65e477608c5b716b2887d20aa7a6319528efb8668712b47e1a6f20c08efbd847e41847c7aa62060795bd0bba069541f58140c81da681d5d9b36f8b5bf74016e1da13bf6b0c3a2f771831d94409b86f9117"
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func keyFromSeed(t *testing.T, seed string) *PrivateKey {
+	t.Helper()
+	sk, err := GenerateKeyFromSeed(decodeHex(t, seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sk
+}
+
+func checkBytes(t *testing.T, name string, got []byte, want string) {
+	t.Helper()
+	if hex.EncodeToString(got) != want {
+		t.Errorf("%v = %x, want %v", name, got, want)
+	}
+}
+
+func TestKeyVectors(t *testing.T) {
+	for _, v := range keyVectors {
+		sk := keyFromSeed(t, v.seed)
+		skBytes, _ := sk.MarshalBinary()
+		checkBytes(t, "GenerateKeyFromSeed", skBytes, v.sk)
+
+		pk := sk.Public().(*PublicKey)
+		pkBytes, _ := pk.MarshalBinary()
+		checkBytes(t, "Public", pkBytes, v.pk)
+		if fingerprint := pk.GetFingerprint(); fingerprint != v.fingerprint {
+			t.Errorf("GetFingerprint = %v, want %v", fingerprint, v.fingerprint)
+		}
+
+		childBytes, _ := sk.DeriveChild([]int{12381, 8444, 2, 0}).MarshalBinary()
+		checkBytes(t, "DeriveChild", childBytes, v.child)
+
+		unhardened, err := sk.DeriveChildUnhardened([]int{12381, 8444, 2, 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		unhardenedBytes, _ := unhardened.MarshalBinary()
+		checkBytes(t, "DeriveChildUnhardened", unhardenedBytes, v.unhardened)
+		unhardenedPk, err := pk.DeriveChildUnhardened([]int{12381, 8444, 2, 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		unhardenedPkBytes, _ := unhardenedPk.MarshalBinary()
+		wantPk, _ := unhardened.Public().(*PublicKey).MarshalBinary()
+		checkBytes(t, "PublicKey.DeriveChildUnhardened", unhardenedPkBytes, hex.EncodeToString(wantPk))
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	for i, v := range keyVectors {
+		sk := keyFromSeed(t, v.seed)
+		sig, err := sk.SignMessage(testMessage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkBytes(t, "SignMessage", sig, v.sig)
+
+		pk := sk.Public().(*PublicKey)
+		if err := pk.Verify(testMessage, sig); err != nil {
+			t.Errorf("Verify = %v", err)
+		}
+		if err := pk.Verify([]byte("another message"), sig); err == nil {
+			t.Error("Verify of another message succeeded")
+		}
+		other := keyFromSeed(t, keyVectors[(i+1)%len(keyVectors)].seed)
+		if other.Public().(*PublicKey).Verify(testMessage, sig) == nil {
+			t.Error("Verify with another key succeeded")
+		}
+	}
+}
+
+func TestAugmentedScheme(t *testing.T) {
+	first := keyFromSeed(t, keyVectors[0].seed)
+	second := keyFromSeed(t, keyVectors[1].seed)
+	secondPk := second.Public().(*PublicKey)
+
+	sig, err := first.SignMessageWithPrependPublicKey(testMessage, secondPk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBytes(t, "SignMessageWithPrependPublicKey", sig, prependVector)
+
+	// a signature with the first public key prepended is a plain SignMessage
+	plain, err := first.SignMessageWithPrependPublicKey(testMessage, first.Public().(*PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBytes(t, "SignMessageWithPrependPublicKey own key", plain, keyVectors[0].sig)
+
+	sigs := [][]byte{decodeHex(t, keyVectors[0].sig), decodeHex(t, keyVectors[1].sig)}
+	aggregate, err := AggregateSignatures(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBytes(t, "AggregateSignatures", aggregate, aggregateVector)
+
+	pks := []*PublicKey{first.Public().(*PublicKey), secondPk}
+	if err := AggregateVerify(pks, [][]byte{testMessage, testMessage}, aggregate); err != nil {
+		t.Errorf("AggregateVerify = %v", err)
+	}
+	if err := AggregateVerify(pks, [][]byte{testMessage, []byte("another message")}, aggregate); err == nil {
+		t.Error("AggregateVerify of another message succeeded")
+	}
+	if _, err := AggregateSignatures(nil); err != ErrEmptyAggregate {
+		t.Errorf("AggregateSignatures(nil) = %v, want %v", err, ErrEmptyAggregate)
+	}
+}
@@ -0,0 +1,63 @@
+package bls
+
+import (
+	"math/big"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// augSchemeDst is the hash to curve domain of chia's AugSchemeMPL
+var augSchemeDst = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_AUG_")
+
+// groupOrder is the order r of G1 and G2
+var groupOrder = bls12381.NewG1().Q()
+
+// scalarBytes encodes a scalar as a 32 bytes big endian private key
+func scalarBytes(s *big.Int) []byte {
+	buf := make([]byte, 32)
+	return s.FillBytes(buf)
+}
+
+// decodeScalar parses a private key, rejecting values not below the group order
+func decodeScalar(data []byte) (*big.Int, error) {
+	if err := checkPrivateKeyData(data); err != nil {
+		return nil, err
+	}
+	s := new(big.Int).SetBytes(data)
+	if s.Cmp(groupOrder) >= 0 {
+		return nil, ErrInvalidKey
+	}
+	return s, nil
+}
+
+// decodeG1 parses a compressed G1 element, checking it is in the subgroup
+func decodeG1(data []byte) (*bls12381.PointG1, error) {
+	if err := checkPublicKeyData(data); err != nil {
+		return nil, err
+	}
+	p, err := bls12381.NewG1().FromCompressed(data)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	return p, nil
+}
+
+// decodeG2 parses a compressed G2 element, checking it is in the subgroup
+func decodeG2(data []byte) (*bls12381.PointG2, error) {
+	if err := checkSignatureData(data); err != nil {
+		return nil, err
+	}
+	p, err := bls12381.NewG2().FromCompressed(data)
+	if err != nil {
+		return nil, ErrInvalidSign
+	}
+	return p, nil
+}
+
+// augHash hashes pk || message to G2 with the augmented scheme domain
+func augHash(pk []byte, message []byte) (*bls12381.PointG2, error) {
+	msg := make([]byte, 0, len(pk)+len(message))
+	msg = append(msg, pk...)
+	msg = append(msg, message...)
+	return bls12381.NewG2().HashToCurve(msg, augSchemeDst)
+}
@@ -0,0 +1,48 @@
+package bls
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// unhardenedNonce is sha256(pk || index) mod r
+func unhardenedNonce(pk []byte, index uint32) *big.Int {
+	buf := make([]byte, len(pk)+4)
+	copy(buf, pk)
+	binary.BigEndian.PutUint32(buf[len(pk):], index)
+	digest := sha256.Sum256(buf)
+	return new(big.Int).Mod(new(big.Int).SetBytes(digest[:]), groupOrder)
+}
+
+// DeriveChildUnhardened derive child private key whose public key can be derived from the parent public key
+func (priv *PrivateKey) DeriveChildUnhardened(paths []int) (*PrivateKey, error) {
+	sk, err := decodeScalar(priv.data)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		pk, _ := (&PrivateKey{data: scalarBytes(sk)}).Public().(*PublicKey).MarshalBinary()
+		sk.Add(sk, unhardenedNonce(pk, uint32(path)))
+		sk.Mod(sk, groupOrder)
+	}
+	return &PrivateKey{data: scalarBytes(sk)}, nil
+}
+
+// DeriveChildUnhardened derive child public key without the private key
+func (pk *PublicKey) DeriveChildUnhardened(paths []int) (*PublicKey, error) {
+	g1 := bls12381.NewG1()
+	p, err := decodeG1(pk.data)
+	if err != nil {
+		return nil, err
+	}
+	data := pk.data
+	for _, path := range paths {
+		offset := g1.MulScalarBig(g1.New(), g1.One(), unhardenedNonce(data, uint32(path)))
+		g1.Add(p, p, offset)
+		data = g1.ToCompressed(p)
+	}
+	return &PublicKey{data: data[:len(data):len(data)]}, nil
+}
@@ -0,0 +1,82 @@
+package bls
+
+import (
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// popSchemeDst is the signature domain of chia's PopSchemeMPL
+var popSchemeDst = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+
+// popProofDst is the proof of possession domain of chia's PopSchemeMPL
+var popProofDst = []byte("BLS_POP_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+
+// signScalar multiplies the hash of msg under domain by the private key
+func (priv *PrivateKey) signScalar(msg []byte, domain []byte) ([]byte, error) {
+	sk, err := decodeScalar(priv.data)
+	if err != nil {
+		return nil, err
+	}
+	g2 := bls12381.NewG2()
+	h, err := g2.HashToCurve(msg, domain)
+	if err != nil {
+		return nil, err
+	}
+	return g2.ToCompressed(g2.MulScalarBig(g2.New(), h, sk)), nil
+}
+
+// verifyScalar checks e(pk, H(msg)) == e(g1, sig) under domain
+func verifyScalar(pk []byte, msg []byte, domain []byte, sig []byte) error {
+	p, err := decodeG1(pk)
+	if err != nil {
+		return err
+	}
+	s, err := decodeG2(sig)
+	if err != nil {
+		return err
+	}
+	engine := bls12381.NewEngine()
+	h, err := engine.G2.HashToCurve(msg, domain)
+	if err != nil {
+		return err
+	}
+	engine.AddPair(p, h)
+	engine.AddPairInv(engine.G1.One(), s)
+	if !engine.Check() {
+		return ErrVerification
+	}
+	return nil
+}
+
+// PopProve creates a proof of possession of the private key
+func (priv *PrivateKey) PopProve() ([]byte, error) {
+	pk, err := priv.Public().(*PublicKey).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return priv.signScalar(pk, popProofDst)
+}
+
+// PopVerify verifies a proof of possession of the public key
+func (pk *PublicKey) PopVerify(proof []byte) error {
+	return verifyScalar(pk.data, pk.data, popProofDst, proof)
+}
+
+// SignMessagePop signs a message with the proof of possession scheme
+func (priv *PrivateKey) SignMessagePop(message []byte) ([]byte, error) {
+	return priv.signScalar(message, popSchemeDst)
+}
+
+// VerifyPop verifies a proof of possession scheme signature
+func (pk *PublicKey) VerifyPop(message []byte, sig []byte) error {
+	return verifyScalar(pk.data, message, popSchemeDst, sig)
+}
+
+// FastAggregateVerify verifies a proof of possession scheme signature of the same message by every key.
+// The keys must have been checked with PopVerify.
+func FastAggregateVerify(pks []*PublicKey, message []byte, sig []byte) error {
+	pk, err := AggregatePublicKeys(pks)
+	if err != nil {
+		return err
+	}
+	return pk.VerifyPop(message, sig)
+}
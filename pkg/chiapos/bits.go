@@ -0,0 +1,138 @@
+package chiapos
+
+import "math/bits"
+
+// bitsAt returns n <= 64 bits of data starting at bit start, most significant bit first
+func bitsAt(data []byte, start uint64, n uint64) uint64 {
+	var value uint64
+	for n > 0 {
+		b := data[start/8]
+		offset := start % 8
+		take := 8 - offset
+		if take > n {
+			take = n
+		}
+		chunk := uint64(b>>(8-offset-take)) & (1<<take - 1)
+		value = value<<take | chunk
+		start += take
+		n -= take
+	}
+	return value
+}
+
+// bitString a string of bits, most significant bit first
+type bitString struct {
+	data []byte
+	size uint64
+}
+
+func (w *bitString) write(value uint64, n uint64) {
+	for n > 0 {
+		if w.size%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		free := 8 - w.size%8
+		take := free
+		if take > n {
+			take = n
+		}
+		chunk := (value >> (n - take)) & (1<<take - 1)
+		w.data[len(w.data)-1] |= byte(chunk << (free - take))
+		w.size += take
+		n -= take
+	}
+}
+
+// appendBits appends n bits of data starting at bit start
+func (w *bitString) appendBits(data []byte, start uint64, n uint64) {
+	for n > 0 {
+		take := n
+		if take > 64 {
+			take = 64
+		}
+		w.write(bitsAt(data, start, take), take)
+		start += take
+		n -= take
+	}
+}
+
+// append appends another bit string
+func (w *bitString) append(b *bitString) {
+	w.appendBits(b.data, 0, b.size)
+}
+
+// slice returns the bits [start, end)
+func (w *bitString) slice(start, end uint64) *bitString {
+	s := &bitString{}
+	s.appendBits(w.data, start, end-start)
+	return s
+}
+
+// uint128 line points of large k do not fit into 64 bits
+type uint128 struct {
+	hi, lo uint64
+}
+
+func (a uint128) add(b uint128) uint128 {
+	lo, carry := bits.Add64(a.lo, b.lo, 0)
+	return uint128{hi: a.hi + b.hi + carry, lo: lo}
+}
+
+func (a uint128) sub(b uint128) uint128 {
+	lo, borrow := bits.Sub64(a.lo, b.lo, 0)
+	return uint128{hi: a.hi - b.hi - borrow, lo: lo}
+}
+
+func (a uint128) lessOrEqual(b uint128) bool {
+	return a.hi < b.hi || a.hi == b.hi && a.lo <= b.lo
+}
+
+func (a uint128) shiftLeft(n uint) uint128 {
+	if n >= 64 {
+		return uint128{hi: a.lo << (n - 64)}
+	}
+	if n == 0 {
+		return a
+	}
+	return uint128{hi: a.hi<<n | a.lo>>(64-n), lo: a.lo << n}
+}
+
+// bits128At returns n <= 128 bits of data starting at bit start
+func bits128At(data []byte, start uint64, n uint64) uint128 {
+	if n <= 64 {
+		return uint128{lo: bitsAt(data, start, n)}
+	}
+	return uint128{hi: bitsAt(data, start, n-64), lo: bitsAt(data, start+n-64, 64)}
+}
+
+// getXEnc returns x * (x - 1) / 2
+func getXEnc(x uint64) uint128 {
+	a, b := x, x-1
+	if x%2 == 0 {
+		a /= 2
+	} else {
+		b /= 2
+	}
+	hi, lo := bits.Mul64(a, b)
+	return uint128{hi: hi, lo: lo}
+}
+
+// squareToLinePoint encodes the pair (x, y) as a single line point
+func squareToLinePoint(x, y uint64) uint128 {
+	if y > x {
+		x, y = y, x
+	}
+	return getXEnc(x).add(uint128{lo: y})
+}
+
+// linePointToSquare decodes a line point into the pair (x, y) with x >= y
+func linePointToSquare(index uint128) (uint64, uint64) {
+	var x uint64
+	for i := 63; i >= 0; i-- {
+		newX := x + 1<<uint(i)
+		if getXEnc(newX).lessOrEqual(index) {
+			x = newX
+		}
+	}
+	return x, index.sub(getXEnc(x)).lo
+}
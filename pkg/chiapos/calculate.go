@@ -0,0 +1,171 @@
+package chiapos
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// chiapos v1 constants
+const (
+	// extraBits bits of x appended to f1, and the extra bits of the f outputs
+	extraBits = 6
+	// f1BlockSizeBits size of a ChaCha8 keystream block
+	f1BlockSizeBits = 512
+)
+
+// vectorLens metadata size of each table, in multiples of k
+var vectorLens = [8]uint64{0, 0, 1, 2, 4, 4, 3, 2}
+
+// f1Calculator evaluates f1, the ChaCha8 keystream keyed by the plot id
+type f1Calculator struct {
+	k     uint64
+	state [16]uint32
+}
+
+func newF1Calculator(k uint32, id []byte) *f1Calculator {
+	f := &f1Calculator{k: uint64(k)}
+	key := make([]byte, 32)
+	key[0] = 1
+	copy(key[1:], id[:31])
+	// "expand 32-byte k"
+	f.state[0], f.state[1], f.state[2], f.state[3] = 0x61707865, 0x3320646e, 0x79622d32, 0x6b206574
+	for i := 0; i < 8; i++ {
+		f.state[4+i] = binary.LittleEndian.Uint32(key[i*4:])
+	}
+	return f
+}
+
+// keystream returns n ChaCha8 blocks starting at block counter
+func (f *f1Calculator) keystream(counter uint64, n int) []byte {
+	out := make([]byte, n*64)
+	input := f.state
+	input[12], input[13] = uint32(counter), uint32(counter>>32)
+	for block := 0; block < n; block++ {
+		x := input
+		for i := 0; i < 8; i += 2 {
+			quarterRound(&x, 0, 4, 8, 12)
+			quarterRound(&x, 1, 5, 9, 13)
+			quarterRound(&x, 2, 6, 10, 14)
+			quarterRound(&x, 3, 7, 11, 15)
+			quarterRound(&x, 0, 5, 10, 15)
+			quarterRound(&x, 1, 6, 11, 12)
+			quarterRound(&x, 2, 7, 8, 13)
+			quarterRound(&x, 3, 4, 9, 14)
+		}
+		for i := range x {
+			binary.LittleEndian.PutUint32(out[block*64+i*4:], x[i]+input[i])
+		}
+		if input[12]++; input[12] == 0 {
+			input[13]++
+		}
+	}
+	return out
+}
+
+func quarterRound(x *[16]uint32, a, b, c, d int) {
+	x[a] += x[b]
+	x[d] = bits.RotateLeft32(x[d]^x[a], 16)
+	x[c] += x[d]
+	x[b] = bits.RotateLeft32(x[b]^x[c], 12)
+	x[a] += x[b]
+	x[d] = bits.RotateLeft32(x[d]^x[a], 8)
+	x[c] += x[d]
+	x[b] = bits.RotateLeft32(x[b]^x[c], 7)
+}
+
+// calculate returns f1(x), k + extraBits bits
+func (f *f1Calculator) calculate(x uint64) uint64 {
+	hi, start := bits.Mul64(x, f.k)
+	counter := hi<<(64-9) | start>>9
+	offset := start % f1BlockSizeBits
+	blocks := 1
+	if offset+f.k > f1BlockSizeBits {
+		blocks = 2
+	}
+	value := bitsAt(f.keystream(counter, blocks), offset, f.k)
+	return value<<extraBits | x>>(f.k-extraBits)
+}
+
+// fx evaluates the f function of table 2 to 7 on the matching entries (y1, left) and
+// (y2, right), returning the output and the metadata of the next table
+func fx(k uint32, table int, y1 uint64, left, right *bitString) (uint64, *bitString) {
+	kk := uint64(k)
+	input := &bitString{}
+	input.write(y1, kk+extraBits)
+	input.append(left)
+	input.append(right)
+
+	// f7 has no extra bits
+	size := kk + extraBits
+	if table == 7 {
+		size = kk
+	}
+	hash := blake3Hash(input.data)
+	f := binary.BigEndian.Uint64(hash[:]) >> (64 - size)
+
+	c := &bitString{}
+	switch {
+	case table < 4:
+		c.append(left)
+		c.append(right)
+	case table < 7:
+		c.appendBits(hash[:], kk+extraBits, kk*vectorLens[table+1])
+	}
+	return f, c
+}
+
+var blake3IV = [8]uint32{0x6A09E667, 0xBB67AE85, 0x3C6EF372, 0xA54FF53A, 0x510E527F, 0x9B05688C, 0x1F83D9AB, 0x5BE0CD19}
+
+var blake3Permutation = [16]int{2, 6, 3, 10, 7, 0, 4, 13, 1, 11, 12, 5, 9, 14, 15, 8}
+
+// blake3Hash hashes at most 64 bytes, which fit into a single BLAKE3 block
+func blake3Hash(input []byte) [32]byte {
+	const (
+		chunkStart = 1 << 0
+		chunkEnd   = 1 << 1
+		root       = 1 << 3
+	)
+	var block [64]byte
+	copy(block[:], input)
+	var m [16]uint32
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint32(block[i*4:])
+	}
+	var s [16]uint32
+	copy(s[:8], blake3IV[:])
+	copy(s[8:12], blake3IV[:4])
+	s[12], s[13] = 0, 0
+	s[14] = uint32(len(input))
+	s[15] = chunkStart | chunkEnd | root
+	for round := 0; round < 7; round++ {
+		blake3G(&s, 0, 4, 8, 12, m[0], m[1])
+		blake3G(&s, 1, 5, 9, 13, m[2], m[3])
+		blake3G(&s, 2, 6, 10, 14, m[4], m[5])
+		blake3G(&s, 3, 7, 11, 15, m[6], m[7])
+		blake3G(&s, 0, 5, 10, 15, m[8], m[9])
+		blake3G(&s, 1, 6, 11, 12, m[10], m[11])
+		blake3G(&s, 2, 7, 8, 13, m[12], m[13])
+		blake3G(&s, 3, 4, 9, 14, m[14], m[15])
+		var permuted [16]uint32
+		for i := range m {
+			permuted[i] = m[blake3Permutation[i]]
+		}
+		m = permuted
+	}
+	var out [32]byte
+	for i := 0; i < 8; i++ {
+		binary.LittleEndian.PutUint32(out[i*4:], s[i]^s[i+8])
+	}
+	return out
+}
+
+func blake3G(s *[16]uint32, a, b, c, d int, mx, my uint32) {
+	s[a] += s[b] + mx
+	s[d] = bits.RotateLeft32(s[d]^s[a], -16)
+	s[c] += s[d]
+	s[b] = bits.RotateLeft32(s[b]^s[c], -12)
+	s[a] += s[b] + my
+	s[d] = bits.RotateLeft32(s[d]^s[a], -8)
+	s[c] += s[d]
+	s[b] = bits.RotateLeft32(s[b]^s[c], -7)
+}
@@ -1,11 +1,11 @@
 package chiapos
 
-import "C"
 import (
 	"bytes"
 	"encoding/hex"
-	"errors"
 	"fmt"
+
+	"github.com/pkg/errors"
 )
 
 var (
@@ -21,13 +21,31 @@ func _Open(fileName string) (*File, error) {
 		fileName: fileName,
 	}
 
-	filePoint, err := CreateDiskProver(fileName)
-	if filePoint == 0 {
+	format, err := ReadPlotFormat(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if format.Compressed() {
+		return nil, errors.Wrapf(ErrCompressedPlot, "compression level %d", format.CompressionLevel)
+	}
+	var p prover
+	if format.Version == PlotVersion1 {
+		p, err = newProver(fileName)
+	} else {
+		// libchiapos only reads v1 plots
+		p, err = newPlotReader(fileName)
+	}
+	if err != nil {
 		return nil, err
 	}
-	file.filePoint = filePoint
+	file.prover = p
+	file.format = format
 	// memo
-	file.memo = file.GetMemo()
+	memo, err := ParseMemo(file.GetMemo())
+	if err != nil {
+		return nil, err
+	}
+	file.memo = memo
 	file.fileName = fileName
 	return file, nil
 }
@@ -51,42 +69,54 @@ type ScannerInfo struct {
 	Lucky           int32
 }
 
+// prover reads a plot, libchiapos DiskProver or the pure Go reader depending on
+// the chiapospure build tag
+type prover interface {
+	GetId() []byte
+	GetMemo() []byte
+	GetSize() uint32
+	GetQualitiesForChallenge(challenge []byte) ([][]byte, int)
+	GetFullProof(challenge []byte, index int) ([]byte, bool)
+	Close()
+}
+
 type File struct {
-	filePoint uintptr
-	fileName  string
-	memo      []byte
+	prover   prover
+	fileName string
+	memo     *Memo
+	format   PlotFormat
 }
 
 func (f *File) GetId() []byte {
-	return GetId(f.filePoint)
+	return f.prover.GetId()
 }
 
 func (f *File) GetMemo() []byte {
-	return GetMemo(f.filePoint)
+	return f.prover.GetMemo()
+}
+
+// GetFormat returns the format version and compression level
+func (f *File) GetFormat() PlotFormat {
+	return f.format
+}
+
+// GetPlotMemo returns the parsed memo
+func (f *File) GetPlotMemo() *Memo {
+	return f.memo
 }
 
+// GetPoolPublicKey returns the pool public key, or the pool contract puzzle hash of NFT plots
 func (f *File) GetPoolPublicKey() (string, error) {
-	if len(f.memo) == 128 {
-		return hex.EncodeToString(f.memo[:48]), nil
-	} else {
-		return hex.EncodeToString(f.memo[:32]), nil
-	}
+	return hex.EncodeToString(f.GetPoolPublicKeyBinary()), nil
 }
 
+// GetPoolPublicKeyBinary returns the pool public key, or the pool contract puzzle hash of NFT plots
 func (f *File) GetPoolPublicKeyBinary() []byte {
-	if len(f.memo) == 128 {
-		return f.memo[:48]
-	} else {
-		return f.memo[:32]
-	}
+	return f.memo.Pool()
 }
 
 func (f *File) GetFarmerPublicKeyBinary() []byte {
-	if len(f.memo) == 128 {
-		return f.memo[48:96]
-	} else {
-		return f.memo[32:80]
-	}
+	return f.memo.FarmerPublicKey
 }
 
 func (f *File) GetFarmerPublicKey() (string, error) {
@@ -98,11 +128,7 @@ func (f *File) GetSecurityKey() (string, error) {
 }
 
 func (f *File) GetSecurityKeyBinary() []byte {
-	if len(f.memo) == 128 {
-		return f.memo[96:]
-	} else {
-		return f.memo[80:]
-	}
+	return f.memo.LocalMasterSecret
 }
 
 func (f *File) GetFilename() string {
@@ -110,11 +136,11 @@ func (f *File) GetFilename() string {
 }
 
 func (f *File) GetSize() uint32 {
-	return GetSize(f.filePoint)
+	return f.prover.GetSize()
 }
 
 func (f *File) GetQualitiesForChallenge(challenge []byte) ([][]byte, int) {
-	qualities, ok := GetQualitiesForChallenge(f.filePoint, challenge, f.getFileHandle())
+	qualities, ok := f.prover.GetQualitiesForChallenge(challenge)
 	if len(qualities) > 0 {
 		for i := 0; i < len(qualities); i++ {
 			if bytes.Equal(qualities[i], emptyQuality) {
@@ -130,9 +156,10 @@ func (f *File) ByteAlign(numBits uint32) uint32 {
 }
 
 func (f *File) GetFullProof(challenge []byte, index int) ([]byte, bool) {
-	return GetFullProof(f.filePoint, challenge, index, f.getFileHandle())
+	return f.prover.GetFullProof(challenge, index)
 }
 
-func (f *File) getFileHandle() int {
-	return int(f.filePoint)
+// Close releases the prover, the file must not be read after Close
+func (f *File) Close() {
+	f.prover.Close()
 }
@@ -1,3 +1,6 @@
+//go:build cgo && !chiapospure
+// +build cgo,!chiapospure
+
 package chiapos
 
 //#cgo CFLAGS: -I./ -DBLAKE3_NO_AVX512=1 -DBLAKE3_NO_SSE41=1 -DBLAKE3_NO_SSE2=1
@@ -5,9 +8,10 @@ package chiapos
 //#cgo linux,amd64 LDFLAGS:-L./c-bindings/libs/linux -lm -lpthread -static -static-libgcc -static-libstdc++
 //#cgo linux,arm LDFLAGS:-L./c-bindings/libs/linux-arm -lm -lpthread -static -static-libgcc -static-libstdc++
 //#cgo linux,arm64 LDFLAGS:-L./c-bindings/libs/linux-arm64 -lm -lpthread -static -static-libgcc -static-libstdc++
-//#cgo darwin,amd64 LDFLAGS:-L./c-bindings/libs/drawin -lm -stdlib=libc++
+//#cgo darwin,amd64 LDFLAGS:-L./c-bindings/libs/darwin -lm -stdlib=libc++
 //#cgo windows,amd64 LDFLAGS:-L./c-bindings/libs/windows -static -static-libgcc -static-libstdc++
 /*
+#include <stdlib.h>
 #include "./c-bindings/include/chiapos.h"
 
 char *getQualitiesIndex(struct Qualities* p, int nIndex){
@@ -28,66 +32,82 @@ func byteToString(data []byte) string {
 	}
 	return ""
 }
-func getFile(dp uintptr) *C.DiskProver {
-	return (*C.DiskProver)(unsafe.Pointer(dp))
+
+// diskProver the DiskProver of libchiapos
+type diskProver struct {
+	dp *C.DiskProver
 }
 
-func CreateDiskProver(fileName string) (uintptr, error) {
+func newProver(fileName string) (prover, error) {
 	message := make([]byte, 1024)
-	var dp uintptr = uintptr(unsafe.Pointer(C.CreateDiskProver(C.CString(fileName), (*C.char)(unsafe.Pointer(&message[0])))))
-	if dp == 0 {
-		return 0, errors.New(byteToString(message))
-	} else {
-		return dp, nil
+	name := C.CString(fileName)
+	defer C.free(unsafe.Pointer(name))
+	dp := C.CreateDiskProver(name, (*C.char)(unsafe.Pointer(&message[0])))
+	if dp == nil {
+		return nil, errors.New(byteToString(message))
 	}
+	return &diskProver{dp: dp}, nil
+}
+
+func (p *diskProver) fileHandle() C.int {
+	return C.int(uintptr(unsafe.Pointer(p.dp)))
 }
 
-func GetQualitiesForChallenge(dp uintptr, challenge []byte, fp int) ([][]byte, int) {
-	var success int = 0
+func (p *diskProver) GetQualitiesForChallenge(challenge []byte) ([][]byte, int) {
+	var success C.int
 	arrChallenge := make([][]byte, 0)
-	p := C.GetQualitiesForChallenge(getFile(dp),
+	q := C.GetQualitiesForChallenge(p.dp,
 		(*C.char)(unsafe.Pointer(&challenge[0])),
-		(*C.int)(unsafe.Pointer(&success)),
-		C.int(fp))
-	if p != nil {
-		for i := 0; i < int(p.nLen); i++ {
+		&success,
+		p.fileHandle())
+	if q != nil {
+		for i := 0; i < int(q.nLen); i++ {
 			buf := make([]byte, 32, 32)
-			ch := C.getQualitiesIndex(p, C.int(i))
+			ch := C.getQualitiesIndex(q, C.int(i))
 			copy(buf, (*[32]byte)(unsafe.Pointer(ch))[:32])
 			arrChallenge = append(arrChallenge, buf)
 		}
-		C.releaseQualities(p)
+		C.releaseQualities(q)
 	}
-	return arrChallenge, success
+	return arrChallenge, int(success)
 }
 
-func GetId(dp uintptr) []byte {
+func (p *diskProver) GetId() []byte {
 	id := make([]byte, IdLen)
-	C.GetId(getFile(dp), (*C.char)(unsafe.Pointer(&id[0])))
+	C.GetId(p.dp, (*C.char)(unsafe.Pointer(&id[0])))
 	return id
 }
 
-func GetMemo(dp uintptr) []byte {
-	nSize := C.GetMemoSize(getFile(dp))
+func (p *diskProver) GetMemo() []byte {
+	nSize := C.GetMemoSize(p.dp)
 	memo := make([]byte, nSize)
-	C.GetMemo(getFile(dp), (*C.char)(unsafe.Pointer(&memo[0])))
+	C.GetMemo(p.dp, (*C.char)(unsafe.Pointer(&memo[0])))
 	return memo
 }
 
-func GetFullProof(dp uintptr, challenge []byte, index int, fp int) ([]byte, bool) {
-	proof := make([]byte, ByteAlign(GetSize(dp)*64)/8)
-	proofSize := C.GetFullProof(getFile(dp),
+func (p *diskProver) GetFullProof(challenge []byte, index int) ([]byte, bool) {
+	proof := make([]byte, ByteAlign(p.GetSize()*64)/8)
+	proofSize := C.GetFullProof(p.dp,
 		(*C.char)(unsafe.Pointer(&challenge[0])),
 		C.uint(index), (*C.char)(unsafe.Pointer(&proof[0])),
-		C.int(fp))
+		p.fileHandle())
 	return proof, proofSize != 0
 }
 
-func GetSize(dp uintptr) uint32 {
-	size := C.GetSize(getFile(dp))
+// Close drops the DiskProver, libchiapos exports no destructor so its memory
+// is not returned before exit
+func (p *diskProver) Close() {
+	p.dp = nil
+}
+
+func (p *diskProver) GetSize() uint32 {
+	size := C.GetSize(p.dp)
 	return uint32(size)
 }
 
+// ValidateProofStatic validates a proof with libchiapos
+//
+// Deprecated: use VerifyProof
 func ValidateProofStatic(id, challenge, proofBytes []byte, k uint32) ([]byte, bool) {
 	quality := make([]byte, 32)
 	ok := C.ValidateProof(
@@ -99,6 +119,7 @@ func ValidateProofStatic(id, challenge, proofBytes []byte, k uint32) ([]byte, bo
 		(*C.char)(unsafe.Pointer(&quality[0])))
 	return quality, bool(ok == 1)
 }
+
 func SetMaxCache(size uint32) {
 	C.setMaxCache(C.uint(size))
 }
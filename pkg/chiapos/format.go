@@ -0,0 +1,214 @@
+package chiapos
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// plot format versions
+const (
+	// PlotVersion1 classic chiapos plots
+	PlotVersion1 = 1
+	// PlotVersion2 chiapos v2 plots written by bladebit, optionally compressed
+	PlotVersion2 = 2
+)
+
+const (
+	plotV1FormatDescription = "v1.0"
+	plotV2Magic             = "PLOT"
+	plotV2FlagCompressed    = 1
+)
+
+var (
+	// ErrNotPlot the file does not start with a plot header
+	ErrNotPlot = errors.New("not a plot file")
+	// ErrUnsupportedVersion the plot format version is unknown
+	ErrUnsupportedVersion = errors.New("unsupported plot format version")
+	// ErrCompressedPlot compressed plots need a decompressing prover
+	ErrCompressedPlot = errors.New("compressed plots are not supported")
+	errBadFormat      = errors.New("invalid plot format")
+)
+
+// skip reasons of plots that can not be farmed
+const (
+	SkipNotPlot            = "not_plot"
+	SkipUnsupportedVersion = "unsupported_version"
+	SkipCompressed         = "compressed"
+	SkipCorrupted          = "corrupted"
+	SkipUnreadable         = "unreadable"
+)
+
+// SkipReason returns the category of a plot loading error
+func SkipReason(err error) string {
+	switch {
+	case errors.Is(err, ErrNotPlot):
+		return SkipNotPlot
+	case errors.Is(err, ErrUnsupportedVersion):
+		return SkipUnsupportedVersion
+	case errors.Is(err, ErrCompressedPlot):
+		return SkipCompressed
+	case errors.Is(err, errBadFormat), errors.Is(err, errBadDeltas), errors.Is(err, ErrInvalidMemo):
+		return SkipCorrupted
+	default:
+		return SkipUnreadable
+	}
+}
+
+// PlotFormat format version and compression level of a plot file
+type PlotFormat struct {
+	Version          int    `json:"version"`
+	CompressionLevel int    `json:"compression_level"` // 0 uncompressed
+	K                uint32 `json:"k"`
+}
+
+func (p PlotFormat) String() string {
+	if p.CompressionLevel > 0 {
+		return fmt.Sprintf("v%d C%d", p.Version, p.CompressionLevel)
+	}
+	return fmt.Sprintf("v%d", p.Version)
+}
+
+// Compressed reports whether the tables are compressed
+func (p PlotFormat) Compressed() bool {
+	return p.CompressionLevel > 0
+}
+
+// plotFileHeader the header of a plot file, tablePointers are the offsets of
+// the tables P1 to P7, C1, C2 and C3
+type plotFileHeader struct {
+	format        PlotFormat
+	id            []byte
+	memo          []byte
+	tablePointers [tablePointerCount + 1]uint64
+}
+
+// PlotHeader format, id and memo of a plot, read without opening a prover
+type PlotHeader struct {
+	Format PlotFormat
+	Id     []byte
+	Memo   []byte
+}
+
+// ReadPlotHeader reads the header of a plot, compressed plots included
+func ReadPlotHeader(fileName string) (*PlotHeader, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	header, err := readPlotHeader(file)
+	if err != nil {
+		return nil, err
+	}
+	return &PlotHeader{Format: header.format, Id: header.id, Memo: header.memo}, nil
+}
+
+// ReadPlotFormat reads the format of a plot from its header
+func ReadPlotFormat(fileName string) (PlotFormat, error) {
+	header, err := ReadPlotHeader(fileName)
+	if err != nil {
+		return PlotFormat{}, err
+	}
+	return header.Format, nil
+}
+
+// readPlotHeader parses a v1 header:
+//
+//	"Proof of Space Plot" | id | k | format description | memo | 10 table pointers (big endian)
+//
+// or a v2 header:
+//
+//	"PLOT" | version (uint32) | id | k | memo | flags (uint32) | [compression level] | 10 table pointers | 10 table sizes
+func readPlotHeader(reader io.Reader) (*plotFileHeader, error) {
+	magic := make([]byte, len(plotV2Magic))
+	if _, err := io.ReadFull(reader, magic); err != nil {
+		return nil, errors.Wrap(ErrNotPlot, err.Error())
+	}
+	header := &plotFileHeader{}
+	switch string(magic) {
+	case plotHeader[:len(plotV2Magic)]:
+		rest := make([]byte, len(plotHeader)-len(plotV2Magic))
+		if _, err := io.ReadFull(reader, rest); err != nil || string(magic)+string(rest) != plotHeader {
+			return nil, ErrNotPlot
+		}
+		header.format.Version = PlotVersion1
+	case plotV2Magic:
+		version := make([]byte, 4)
+		if _, err := io.ReadFull(reader, version); err != nil {
+			return nil, errors.Wrap(errBadFormat, err.Error())
+		}
+		if v := binary.LittleEndian.Uint32(version); v != PlotVersion2 {
+			return nil, errors.Wrapf(ErrUnsupportedVersion, "version %d", v)
+		}
+		header.format.Version = PlotVersion2
+	default:
+		return nil, ErrNotPlot
+	}
+
+	idK := make([]byte, IdLen+1)
+	if _, err := io.ReadFull(reader, idK); err != nil {
+		return nil, errors.Wrap(errBadFormat, err.Error())
+	}
+	header.id = idK[:IdLen]
+	header.format.K = uint32(idK[IdLen])
+	if header.format.K < extraBits+stubMinusBits || header.format.K > 59 {
+		return nil, errors.Wrapf(errBadFormat, "k %d", header.format.K)
+	}
+
+	var err error
+	order := binary.ByteOrder(binary.LittleEndian)
+	if header.format.Version == PlotVersion1 {
+		description, err := readSized(reader)
+		if err != nil {
+			return nil, err
+		}
+		if string(description) != plotV1FormatDescription {
+			return nil, errors.Wrapf(ErrUnsupportedVersion, "format %q", description)
+		}
+		if header.memo, err = readSized(reader); err != nil {
+			return nil, err
+		}
+		order = binary.BigEndian
+	} else {
+		if header.memo, err = readSized(reader); err != nil {
+			return nil, err
+		}
+		flags := make([]byte, 4)
+		if _, err := io.ReadFull(reader, flags); err != nil {
+			return nil, errors.Wrap(errBadFormat, err.Error())
+		}
+		if binary.LittleEndian.Uint32(flags)&plotV2FlagCompressed != 0 {
+			level := make([]byte, 1)
+			if _, err := io.ReadFull(reader, level); err != nil {
+				return nil, errors.Wrap(errBadFormat, err.Error())
+			}
+			header.format.CompressionLevel = int(level[0])
+		}
+	}
+
+	pointers := make([]byte, tablePointerCount*8)
+	if _, err := io.ReadFull(reader, pointers); err != nil {
+		return nil, errors.Wrap(errBadFormat, err.Error())
+	}
+	for i := 1; i <= tablePointerCount; i++ {
+		header.tablePointers[i] = order.Uint64(pointers[(i-1)*8:])
+	}
+	return header, nil
+}
+
+// readSized reads a big endian 2 bytes length followed by the data
+func readSized(reader io.Reader) ([]byte, error) {
+	size := make([]byte, 2)
+	if _, err := io.ReadFull(reader, size); err != nil {
+		return nil, errors.Wrap(errBadFormat, err.Error())
+	}
+	data := make([]byte, binary.BigEndian.Uint16(size))
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, errors.Wrap(errBadFormat, err.Error())
+	}
+	return data, nil
+}
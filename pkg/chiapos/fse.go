@@ -0,0 +1,214 @@
+package chiapos
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// fseTableLog the deltas of the parks and of C3 are compressed with FSE (finite
+// state entropy), using a fixed distribution per table
+const fseTableLog = 14
+
+var errBadDeltas = errors.New("invalid park deltas")
+
+type fseDecode struct {
+	newState uint16
+	symbol   uint8
+	nbBits   uint8
+}
+
+var (
+	fseTables      = make(map[float64][]fseDecode)
+	fseTablesMutex sync.Mutex
+)
+
+// createNormalizedCount returns the FSE normalized counts of the deltas
+// distribution with parameter r, same as Encoding::CreateNormalizedCount
+func createNormalizedCount(r float64) []int16 {
+	const (
+		minProbabilityThreshold = 1e-50
+		totalQuanta             = 1 << fseTableLog
+	)
+	e := 2.71828182845904523536
+	var dpdf []float64
+	n := 0
+	p := 1 - math.Pow((e-1)/e, 1.0/r)
+	for p > minProbabilityThreshold && n < 255 {
+		dpdf = append(dpdf, p)
+		n++
+		p = (math.Pow(e, 1.0/r) - 1) * math.Pow(e-1, 1.0/r)
+		p = p / math.Pow(e, float64(n+1)/r)
+	}
+
+	ans := make([]int, n)
+	for i := range ans {
+		ans[i] = 1
+	}
+	less := func(i, j int) bool {
+		return dpdf[i]*(math.Log2(float64(ans[i]+1))-math.Log2(float64(ans[i]))) <
+			dpdf[j]*(math.Log2(float64(ans[j]+1))-math.Log2(float64(ans[j])))
+	}
+	// the order ties are taken in matters, so this is the binary heap of std::priority_queue
+	heap := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		heap = append(heap, i)
+		heapPush(heap, len(heap)-1, 0, i, less)
+	}
+	for todo := 0; todo < totalQuanta-n; todo++ {
+		top := heap[0]
+		last := len(heap) - 1
+		value := heap[last]
+		heap[last] = top
+		heapAdjust(heap, 0, last, value, less)
+		heap = heap[:last]
+
+		ans[top]++
+		heap = append(heap, top)
+		heapPush(heap, len(heap)-1, 0, top, less)
+	}
+
+	count := make([]int16, n)
+	for i, a := range ans {
+		if a == 1 {
+			count[i] = -1
+		} else {
+			count[i] = int16(a)
+		}
+	}
+	return count
+}
+
+// heapPush std::__push_heap
+func heapPush(heap []int, hole, top, value int, less func(i, j int) bool) {
+	parent := (hole - 1) / 2
+	for hole > top && less(heap[parent], value) {
+		heap[hole] = heap[parent]
+		hole = parent
+		parent = (hole - 1) / 2
+	}
+	heap[hole] = value
+}
+
+// heapAdjust std::__adjust_heap
+func heapAdjust(heap []int, hole, length, value int, less func(i, j int) bool) {
+	top := hole
+	child := hole
+	for child < (length-1)/2 {
+		child = 2 * (child + 1)
+		if less(heap[child], heap[child-1]) {
+			child--
+		}
+		heap[hole] = heap[child]
+		hole = child
+	}
+	if length%2 == 0 && child == (length-2)/2 {
+		child = 2 * (child + 1)
+		heap[hole] = heap[child-1]
+		hole = child - 1
+	}
+	heapPush(heap, hole, top, value, less)
+}
+
+// buildFseTable builds the decoding table of the normalized counts, same as FSE_buildDTable
+func buildFseTable(count []int16) []fseDecode {
+	tableSize := uint32(1) << fseTableLog
+	table := make([]fseDecode, tableSize)
+	next := make([]uint32, len(count))
+	high := tableSize - 1
+	for s, c := range count {
+		if c == -1 {
+			table[high].symbol = uint8(s)
+			high--
+			next[s] = 1
+		} else {
+			next[s] = uint32(c)
+		}
+	}
+
+	mask := tableSize - 1
+	step := tableSize>>1 + tableSize>>3 + 3
+	position := uint32(0)
+	for s, c := range count {
+		for i := 0; i < int(c); i++ {
+			table[position].symbol = uint8(s)
+			position = (position + step) & mask
+			for position > high {
+				position = (position + step) & mask
+			}
+		}
+	}
+
+	for u := range table {
+		state := next[table[u].symbol]
+		next[table[u].symbol]++
+		nbBits := fseTableLog - (31 - uint32(bits.LeadingZeros32(state)))
+		table[u].nbBits = uint8(nbBits)
+		table[u].newState = uint16(state<<nbBits - tableSize)
+	}
+	return table
+}
+
+func fseTable(r float64) []fseDecode {
+	fseTablesMutex.Lock()
+	defer fseTablesMutex.Unlock()
+	table, ok := fseTables[r]
+	if !ok {
+		table = buildFseTable(createNormalizedCount(r))
+		fseTables[r] = table
+	}
+	return table
+}
+
+// fseReader reads the bit stream backwards, from the end marker to the first byte
+type fseReader struct {
+	data     []byte
+	position int // bits left to read
+}
+
+func (b *fseReader) read(n uint8) uint32 {
+	var value uint32
+	for i := uint8(0); i < n; i++ {
+		b.position--
+		value <<= 1
+		if b.position >= 0 {
+			value |= uint32(b.data[b.position/8]>>(b.position%8)) & 1
+		}
+	}
+	return value
+}
+
+// ansDecodeDeltas decodes the deltas of a park, returns numDeltas bytes padded with zeros
+func ansDecodeDeltas(data []byte, numDeltas int, r float64) ([]byte, error) {
+	if len(data) == 0 || data[len(data)-1] == 0 {
+		return nil, errBadDeltas
+	}
+	table := fseTable(r)
+	last := data[len(data)-1]
+	reader := &fseReader{data: data, position: len(data)*8 - (8 - (7 - bits.LeadingZeros8(last)))}
+	var states [2]uint32
+	states[0] = reader.read(fseTableLog)
+	states[1] = reader.read(fseTableLog)
+
+	deltas := make([]byte, 0, numDeltas)
+	for i := 0; ; i ^= 1 {
+		if len(deltas) > numDeltas-2 {
+			return nil, errBadDeltas
+		}
+		decode := table[states[i]]
+		deltas = append(deltas, decode.symbol)
+		states[i] = uint32(decode.newState) + reader.read(decode.nbBits)
+		if reader.position < 0 {
+			deltas = append(deltas, table[states[i^1]].symbol)
+			break
+		}
+	}
+	for _, delta := range deltas {
+		if delta == 0xff {
+			return nil, errBadDeltas
+		}
+	}
+	return append(deltas, make([]byte, numDeltas-len(deltas))...), nil
+}
@@ -0,0 +1,82 @@
+package chiapos
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	// PoolPublicKeyMemoLen memo length of plots with a pool public key
+	PoolPublicKeyMemoLen = 48 + 48 + 32
+	// PoolContractMemoLen memo length of plots with a pool contract puzzle hash
+	PoolContractMemoLen = 32 + 48 + 32
+)
+
+// pool kinds of a plot, sent with every proof
+const (
+	PoolKindPublicKey = "pool_public_key"
+	PoolKindContract  = "pool_contract_puzzle_hash"
+)
+
+// ErrInvalidMemo memo length matches neither plot kind
+var ErrInvalidMemo = errors.New("invalid plot memo")
+
+// Memo keys stored in the plot header. Exactly one of PoolPublicKey and
+// PoolContractPuzzleHash is set.
+type Memo struct {
+	PoolPublicKey          []byte
+	PoolContractPuzzleHash []byte
+	FarmerPublicKey        []byte
+	LocalMasterSecret      []byte
+}
+
+// ParseMemo splits a plot memo into its keys
+func ParseMemo(memo []byte) (*Memo, error) {
+	var poolLen int
+	switch len(memo) {
+	case PoolPublicKeyMemoLen:
+		poolLen = 48
+	case PoolContractMemoLen:
+		poolLen = 32
+	default:
+		return nil, errors.Wrapf(ErrInvalidMemo, "length %v", len(memo))
+	}
+	m := &Memo{
+		FarmerPublicKey:   memo[poolLen : poolLen+48],
+		LocalMasterSecret: memo[poolLen+48:],
+	}
+	if poolLen == 48 {
+		m.PoolPublicKey = memo[:poolLen]
+	} else {
+		m.PoolContractPuzzleHash = memo[:poolLen]
+	}
+	return m, nil
+}
+
+// IsPoolContract reports whether the plot was created for a pool contract (NFT plot)
+func (m *Memo) IsPoolContract() bool {
+	return m.PoolContractPuzzleHash != nil
+}
+
+// PoolKind returns PoolKindPublicKey or PoolKindContract
+func (m *Memo) PoolKind() string {
+	if m.IsPoolContract() {
+		return PoolKindContract
+	}
+	return PoolKindPublicKey
+}
+
+// Pool returns the pool public key or the pool contract puzzle hash, the plot id is derived from it
+func (m *Memo) Pool() []byte {
+	if m.IsPoolContract() {
+		return m.PoolContractPuzzleHash
+	}
+	return m.PoolPublicKey
+}
+
+// Bytes returns the memo as stored in the plot
+func (m *Memo) Bytes() []byte {
+	data := make([]byte, 0, PoolPublicKeyMemoLen)
+	data = append(data, m.Pool()...)
+	data = append(data, m.FarmerPublicKey...)
+	return append(data, m.LocalMasterSecret...)
+}
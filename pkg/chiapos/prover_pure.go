@@ -0,0 +1,19 @@
+//go:build !cgo || chiapospure
+// +build !cgo chiapospure
+
+package chiapos
+
+func newProver(fileName string) (prover, error) {
+	return newPlotReader(fileName)
+}
+
+// ValidateProofStatic same as VerifyProof
+//
+// Deprecated: use VerifyProof
+func ValidateProofStatic(id, challenge, proofBytes []byte, k uint32) ([]byte, bool) {
+	return VerifyProof(id, k, challenge, proofBytes)
+}
+
+// SetMaxCache the pure Go reader does not cache
+func SetMaxCache(size uint32) {
+}
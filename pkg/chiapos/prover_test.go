@@ -0,0 +1,74 @@
+package chiapos
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+)
+
+// testPlot a k14 chiapos v1 plot, small enough that a third of the random
+// challenges have proofs. Its memo holds valid pool, farmer and local keys and
+// its id is derived from them like the id of a real plot. The proofs of the
+// test challenges were read with libchiapos.
+const (
+	testPlot       = "testdata/k14.plot"
+	testChallenges = 400
+	// testProofs proofs of the test challenges and testDigest the sha256 of
+	// their qualities and proofs, in order
+	testProofs = 174
+	testDigest = "1450861c0614f89989fe23c80a5b9393de55db4533b98e1a4ac7a71b5121cc9b"
+)
+
+func challenges() [][]byte {
+	rng := rand.New(rand.NewSource(1))
+	list := make([][]byte, testChallenges)
+	for i := range list {
+		list[i] = make([]byte, 32)
+		rng.Read(list[i])
+	}
+	return list
+}
+
+// TestPlotReader compares the pure Go reader with the prover of the build,
+// libchiapos with cgo, and with the qualities and proofs read with libchiapos
+func TestPlotReader(t *testing.T) {
+	reader, err := newPlotReader(testPlot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := newProver(testPlot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reader.GetId(), p.GetId()) || !bytes.Equal(reader.GetMemo(), p.GetMemo()) || reader.GetSize() != p.GetSize() {
+		t.Fatal("plot header differs")
+	}
+
+	digest := sha256.New()
+	proofs := 0
+	for i, challenge := range challenges() {
+		qualities, _ := reader.GetQualitiesForChallenge(challenge)
+		want, _ := p.GetQualitiesForChallenge(challenge)
+		if len(qualities) != len(want) {
+			t.Fatalf("challenge %d: %d qualities, want %d", i, len(qualities), len(want))
+		}
+		for index, quality := range qualities {
+			if !bytes.Equal(quality, want[index]) {
+				t.Fatalf("challenge %d quality %d = %x, want %x", i, index, quality, want[index])
+			}
+			proof, ok := reader.GetFullProof(challenge, index)
+			wantProof, wantOk := p.GetFullProof(challenge, index)
+			if ok != wantOk || !bytes.Equal(proof, wantProof) {
+				t.Fatalf("challenge %d proof %d = %x, want %x", i, index, proof, wantProof)
+			}
+			digest.Write(quality)
+			digest.Write(proof)
+			proofs++
+		}
+	}
+	if proofs != testProofs || hex.EncodeToString(digest.Sum(nil)) != testDigest {
+		t.Errorf("%d proofs digest %x, want %d proofs digest %v", proofs, digest.Sum(nil), testProofs, testDigest)
+	}
+}
@@ -0,0 +1,449 @@
+package chiapos
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// chiapos plot format constants
+const (
+	plotHeader        = "Proof of Space Plot"
+	checkpoint1       = 10000
+	checkpoint2       = 10000
+	entriesPerPark    = 2048
+	stubMinusBits     = 3
+	maxAverageDelta1  = 5.6
+	maxAverageDelta   = 3.5
+	c3BitsPerEntry    = 2.4
+	c3R               = 1.0
+	tablePointerCount = 10
+)
+
+// rValues parameter of the deltas distribution of tables 1 to 6
+var rValues = [6]float64{4.7, 2.75, 2.75, 2.7, 2.6, 2.45}
+
+var errNoProof = errors.New("no proof of space for this challenge")
+
+// plotReader reads qualities and proofs of an uncompressed plot, the file is
+// opened for each lookup like the DiskProver does
+type plotReader struct {
+	fileName      string
+	id            []byte
+	k             uint32
+	memo          []byte
+	tablePointers [tablePointerCount + 1]uint64
+	c2            []uint64
+	f1            *f1Calculator
+}
+
+func newPlotReader(fileName string) (*plotReader, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header, err := readPlotHeader(bufio.NewReader(file))
+	if err != nil {
+		return nil, err
+	}
+	if header.format.Compressed() {
+		return nil, errors.Wrapf(ErrCompressedPlot, "compression level %d", header.format.CompressionLevel)
+	}
+	r := &plotReader{
+		fileName:      fileName,
+		id:            header.id,
+		k:             header.format.K,
+		memo:          header.memo,
+		tablePointers: header.tablePointers,
+	}
+
+	// C2 is small enough to keep in memory
+	c2Size := uint64(ByteAlign(r.k) / 8)
+	c2Entries := (r.tablePointers[10] - r.tablePointers[9]) / c2Size
+	if r.tablePointers[10] < r.tablePointers[9] || c2Entries <= 1 {
+		return nil, errors.Wrap(errBadFormat, "invalid C2 table size")
+	}
+	c2 := make([]byte, (c2Entries-1)*c2Size)
+	if _, err := file.ReadAt(c2, int64(r.tablePointers[9])); err != nil {
+		return nil, errors.Wrap(errBadFormat, err.Error())
+	}
+	r.c2 = make([]uint64, 0, c2Entries-1)
+	for i := uint64(0); i < c2Entries-1; i++ {
+		r.c2 = append(r.c2, bitsAt(c2[i*c2Size:], 0, uint64(r.k)))
+	}
+	r.f1 = newF1Calculator(r.k, r.id)
+	return r, nil
+}
+
+// Close drops the in memory tables, the file itself is only open during lookups
+func (r *plotReader) Close() {
+	r.c2 = nil
+	r.f1 = nil
+}
+
+func (r *plotReader) GetId() []byte {
+	return r.id
+}
+
+func (r *plotReader) GetMemo() []byte {
+	return r.memo
+}
+
+func (r *plotReader) GetSize() uint32 {
+	return r.k
+}
+
+func (r *plotReader) GetQualitiesForChallenge(challenge []byte) ([][]byte, int) {
+	qualities, err := r.qualities(challenge)
+	if err != nil {
+		return nil, 0
+	}
+	return qualities, 1
+}
+
+func (r *plotReader) GetFullProof(challenge []byte, index int) ([]byte, bool) {
+	proof, err := r.fullProof(challenge, index)
+	if err != nil {
+		return nil, false
+	}
+	return proof, true
+}
+
+// qualities follows one branch of each proof, chosen by the last 5 bits of the
+// challenge, down to table 1 and hashes its two x values with the challenge
+func (r *plotReader) qualities(challenge []byte) ([][]byte, error) {
+	file, err := os.Open(r.fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries, err := r.getP7Entries(file, challenge)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	last5Bits := challenge[31] & 0x1f
+	qualities := make([][]byte, 0, len(entries))
+	for _, position := range entries {
+		for table := 6; table > 1; table-- {
+			linePoint, err := r.readLinePoint(file, table, position)
+			if err != nil {
+				return nil, err
+			}
+			x, y := linePointToSquare(linePoint)
+			if (last5Bits>>(table-2))&1 == 0 {
+				position = y
+			} else {
+				position = x
+			}
+		}
+		linePoint, err := r.readLinePoint(file, 1, position)
+		if err != nil {
+			return nil, err
+		}
+		x1, x2 := linePointToSquare(linePoint)
+		input := &bitString{data: append([]byte{}, challenge[:32]...), size: 256}
+		input.write(x2, uint64(r.k))
+		input.write(x1, uint64(r.k))
+		quality := sha256.Sum256(input.data)
+		qualities = append(qualities, quality[:])
+	}
+	return qualities, nil
+}
+
+// fullProof reads the 64 x values of a proof, in proof order
+func (r *plotReader) fullProof(challenge []byte, index int) ([]byte, error) {
+	file, err := os.Open(r.fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries, err := r.getP7Entries(file, challenge)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(entries) {
+		return nil, errNoProof
+	}
+	xs := make([]uint64, 0, 64)
+	if xs, err = r.getInputs(file, entries[index], 6, xs); err != nil {
+		return nil, err
+	}
+	return r.reorderProof(xs), nil
+}
+
+// getInputs returns the x values under position of table
+func (r *plotReader) getInputs(file *os.File, position uint64, table int, xs []uint64) ([]uint64, error) {
+	linePoint, err := r.readLinePoint(file, table, position)
+	if err != nil {
+		return nil, err
+	}
+	x, y := linePointToSquare(linePoint)
+	if table == 1 {
+		return append(xs, y, x), nil
+	}
+	if xs, err = r.getInputs(file, y, table-1, xs); err != nil {
+		return nil, err
+	}
+	return r.getInputs(file, x, table-1, xs)
+}
+
+type fxEntry struct {
+	y        uint64
+	metadata *bitString
+}
+
+// reorderProof sorts the x values from plot order into proof order, where f1(x0)
+// matches f1(x1), f2(x0, x1) matches f2(x2, x3) and so on
+func (r *plotReader) reorderProof(input []uint64) []byte {
+	k := uint64(r.k)
+	results := make([]fxEntry, 0, 64)
+	xs := &bitString{}
+	for _, x := range input {
+		metadata := &bitString{}
+		metadata.write(x, k)
+		results = append(results, fxEntry{y: r.f1.calculate(x), metadata: metadata})
+		xs.write(x, k)
+	}
+	for table := 2; table < 8; table++ {
+		newXs := &bitString{}
+		newResults := make([]fxEntry, 0, len(results)/2)
+		size := k << uint(table-2)
+		for i := 0; i < len(results); i += 2 {
+			left, right := results[i], results[i+1]
+			start := uint64(i) * size
+			middle := start + size
+			end := middle + size
+			if left.y < right.y {
+				newXs.append(xs.slice(start, end))
+			} else {
+				left, right = right, left
+				newXs.append(xs.slice(middle, end))
+				newXs.append(xs.slice(start, middle))
+			}
+			f, metadata := fx(r.k, table, left.y, left.metadata, right.metadata)
+			newResults = append(newResults, fxEntry{y: f, metadata: metadata})
+		}
+		results = newResults
+		xs = newXs
+	}
+	return xs.data
+}
+
+// parkSize size of a park of table 1 to 6: the first line point, the stubs and the
+// compressed deltas of the other entries
+func (r *plotReader) parkSize(table int) uint64 {
+	return r.linePointSize() + r.stubsSize() + maxDeltasSize(table)
+}
+
+func (r *plotReader) linePointSize() uint64 {
+	return uint64(ByteAlign(2*r.k) / 8)
+}
+
+func (r *plotReader) stubsSize() uint64 {
+	return uint64(ByteAlign((entriesPerPark-1)*(r.k-stubMinusBits)) / 8)
+}
+
+func maxDeltasSize(table int) uint64 {
+	average := maxAverageDelta
+	if table == 1 {
+		average = maxAverageDelta1
+	}
+	return uint64(ByteAlign(uint32(float64(entriesPerPark-1)*average)) / 8)
+}
+
+// c3Size size of a C3 park, the deltas of f7 between two C1 checkpoints
+func (r *plotReader) c3Size() uint64 {
+	if r.k < 20 {
+		return uint64(ByteAlign(8*checkpoint1) / 8)
+	}
+	return uint64(ByteAlign(uint32(c3BitsPerEntry*checkpoint1)) / 8)
+}
+
+// readLinePoint returns the line point at position of table, the park stores
+// the first line point, then the stub and the delta to the previous entry of the others
+func (r *plotReader) readLinePoint(file *os.File, table int, position uint64) (uint128, error) {
+	park := make([]byte, r.parkSize(table))
+	if _, err := file.ReadAt(park, int64(r.tablePointers[table]+r.parkSize(table)*(position/entriesPerPark))); err != nil {
+		return uint128{}, errors.Wrap(errBadFormat, err.Error())
+	}
+	k := uint64(r.k)
+	linePoint := bits128At(park, 0, 2*k)
+	stubs := park[r.linePointSize() : r.linePointSize()+r.stubsSize()]
+	deltasData := park[r.linePointSize()+r.stubsSize():]
+
+	size := binary.LittleEndian.Uint16(deltasData)
+	var deltas []byte
+	if size&0x8000 != 0 {
+		size &= 0x7fff
+		if uint64(size)+2 > uint64(len(deltasData)) {
+			return uint128{}, errors.Wrapf(errBadDeltas, "size %d", size)
+		}
+		deltas = deltasData[2 : 2+size]
+	} else {
+		if uint64(size)+2 > uint64(len(deltasData)) {
+			return uint128{}, errors.Wrapf(errBadDeltas, "size %d", size)
+		}
+		var err error
+		if deltas, err = ansDecodeDeltas(deltasData[2:2+size], entriesPerPark-1, rValues[table-1]); err != nil {
+			return uint128{}, err
+		}
+	}
+
+	stubSize := k - stubMinusBits
+	var sumDeltas, sumStubs uint64
+	for i := uint64(0); i < position%entriesPerPark && i < uint64(len(deltas)); i++ {
+		sumStubs += bitsAt(stubs, i*stubSize, stubSize)
+		sumDeltas += uint64(deltas[i])
+	}
+	bigDelta := uint128{lo: sumDeltas}.shiftLeft(uint(stubSize)).add(uint128{lo: sumStubs})
+	return linePoint.add(bigDelta), nil
+}
+
+// getP7Entries returns the positions into table 6 of the proofs whose f7 equals
+// the first k bits of the challenge, located through C2, C1 and C3
+func (r *plotReader) getP7Entries(file *os.File, challenge []byte) ([]uint64, error) {
+	if len(r.c2) == 0 {
+		return nil, nil
+	}
+	k := uint64(r.k)
+	f7 := bitsAt(challenge, 0, k)
+
+	c1Index := int64(0)
+	broke := false
+	var c2EntryF uint64
+	for _, c2Entry := range r.c2 {
+		c2EntryF = c2Entry
+		if f7 < c2Entry {
+			c1Index -= checkpoint2
+			broke = true
+			break
+		}
+		c1Index += checkpoint2
+	}
+	if c1Index < 0 {
+		return nil, nil
+	}
+	if !broke {
+		c1Index -= checkpoint2
+	}
+
+	// C1 entries from the C2 checkpoint on
+	c1Size := uint64(ByteAlign(r.k) / 8)
+	c1 := make([]byte, c1Size*checkpoint1)
+	n, err := file.ReadAt(c1, int64(r.tablePointers[8]+uint64(c1Index)*c1Size))
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(errBadFormat, err.Error())
+	}
+	c1 = c1[:uint64(n)/c1Size*c1Size]
+	currF7, prevF7 := c2EntryF, c2EntryF
+	broke = false
+	for start := uint64(0); start*c1Size < uint64(len(c1)); start++ {
+		readF7 := bitsAt(c1[start*c1Size:], 0, k)
+		if start != 0 && readF7 == 0 {
+			break
+		}
+		currF7 = readF7
+		if f7 < currF7 {
+			currF7 = prevF7
+			c1Index--
+			broke = true
+			break
+		}
+		c1Index++
+		prevF7 = currF7
+	}
+	if !broke {
+		c1Index--
+	}
+
+	// entries in two C3 parks when f7 equals the checkpoint
+	doubleEntry := f7 == currF7 && c1Index > 0
+	var positions []uint64
+	if doubleEntry {
+		c1Index--
+		entry := make([]byte, c1Size)
+		if _, err := file.ReadAt(entry, int64(r.tablePointers[8]+uint64(c1Index)*c1Size)); err != nil {
+			return nil, errors.Wrap(errBadFormat, err.Error())
+		}
+		nextF7 := currF7
+		currF7 = bitsAt(entry, 0, k)
+		first, err := r.getP7Positions(file, currF7, f7, c1Index)
+		if err != nil {
+			return nil, err
+		}
+		second, err := r.getP7Positions(file, nextF7, f7, c1Index+1)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(first, second...)
+	} else {
+		if positions, err = r.getP7Positions(file, currF7, f7, c1Index); err != nil {
+			return nil, err
+		}
+	}
+	if len(positions) == 0 {
+		return nil, nil
+	}
+
+	// table P7 maps the positions to table 6
+	parkSize := uint64(ByteAlign((r.k+1)*entriesPerPark) / 8)
+	park := make([]byte, parkSize)
+	parkIndex := ^uint64(0)
+	entries := make([]uint64, 0, len(positions))
+	for _, position := range positions {
+		if position/entriesPerPark != parkIndex {
+			parkIndex = position / entriesPerPark
+			if _, err := file.ReadAt(park, int64(r.tablePointers[7]+parkIndex*parkSize)); err != nil {
+				return nil, errors.Wrap(errBadFormat, err.Error())
+			}
+		}
+		entries = append(entries, bitsAt(park, position%entriesPerPark*(k+1), k+1))
+	}
+	return entries, nil
+}
+
+// getP7Positions decodes the C3 park of c1Index and returns the positions of f7
+func (r *plotReader) getP7Positions(file *os.File, currF7, f7 uint64, c1Index int64) ([]uint64, error) {
+	park := make([]byte, r.c3Size())
+	if _, err := file.ReadAt(park, int64(r.tablePointers[10]+uint64(c1Index)*r.c3Size())); err != nil {
+		return nil, errors.Wrap(errBadFormat, err.Error())
+	}
+	size := uint64(binary.BigEndian.Uint16(park))
+	if size > r.c3Size()-2 {
+		return nil, nil
+	}
+	deltas, err := ansDecodeDeltas(park[2:2+size], checkpoint1, c3R)
+	if err != nil {
+		return nil, err
+	}
+	position := uint64(c1Index) * checkpoint1
+	var positions []uint64
+	surpassed := false
+	for _, delta := range deltas {
+		if currF7 > f7 {
+			surpassed = true
+			break
+		}
+		currF7 += uint64(delta)
+		position++
+		if currF7 == f7 {
+			positions = append(positions, position)
+		}
+		// the last park has no end marker, the deltas are padded with zeros
+		if position >= uint64(c1Index+1)*checkpoint1-1 || currF7 >= 1<<r.k-1 {
+			break
+		}
+	}
+	if !surpassed {
+		return nil, nil
+	}
+	return positions, nil
+}
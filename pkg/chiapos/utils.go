@@ -59,3 +59,14 @@ func CalculateIterationsQuality(qualityData []byte, size int32, difficulty uint6
 func ByteAlign(numBits uint32) uint32 {
 	return numBits + (8-((numBits)%8))%8
 }
+
+// ExpectedPlotSize implementation _expected_plot_size(), ((2 * k) + 1) * (2 ** (k - 1))
+func ExpectedPlotSize(k uint32) uint64 {
+	return uint64(2*k+1) << (k - 1)
+}
+
+// PlotFileSize approximate size of a k plot on disk in bytes,
+// the expected plot size times UI_ACTUAL_SPACE_CONSTANT_FACTOR (0.762)
+func PlotFileSize(k uint32) uint64 {
+	return uint64(float64(ExpectedPlotSize(k)) * 0.762)
+}
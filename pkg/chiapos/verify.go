@@ -0,0 +1,101 @@
+package chiapos
+
+import (
+	"crypto/sha256"
+)
+
+// matching constants, entries of adjacent buckets of kBC match
+const (
+	kB  = 119
+	kC  = 127
+	kBC = kB * kC
+)
+
+// VerifyProof checks a full proof of a k plot against the challenge and returns
+// its quality string, same as the chiapos Verifier
+func VerifyProof(id []byte, k uint32, challenge, proof []byte) ([]byte, bool) {
+	kk := uint64(k)
+	if len(id) != IdLen || len(challenge) != 32 || k < extraBits || uint64(len(proof))*8 != kk*64 {
+		return nil, false
+	}
+	f1 := newF1Calculator(k, id)
+	results := make([]fxEntry, 0, 64)
+	for i := uint64(0); i < 64; i++ {
+		x := bitsAt(proof, i*kk, kk)
+		metadata := &bitString{}
+		metadata.write(x, kk)
+		results = append(results, fxEntry{y: f1.calculate(x), metadata: metadata})
+	}
+	for table := 2; table < 8; table++ {
+		newResults := make([]fxEntry, 0, len(results)/2)
+		for i := 0; i < len(results); i += 2 {
+			left, right := results[i], results[i+1]
+			if !matches(left.y, right.y) {
+				return nil, false
+			}
+			f, metadata := fx(k, table, left.y, left.metadata, right.metadata)
+			newResults = append(newResults, fxEntry{y: f, metadata: metadata})
+		}
+		results = newResults
+	}
+	if results[0].y != bitsAt(challenge, 0, kk) {
+		return nil, false
+	}
+	return qualityString(k, proof, uint64(challenge[31]&0x1f)<<1, challenge), true
+}
+
+// matches reports whether yl and yr, of adjacent buckets, match
+func matches(yl, yr uint64) bool {
+	if yr/kBC != yl/kBC+1 {
+		return false
+	}
+	parity := (yl / kBC) % 2
+	left, right := yl%kBC, yr%kBC
+	for m := uint64(0); m < 1<<extraBits; m++ {
+		target := ((left/kC+m)%kB)*kC + ((2*m+parity)*(2*m+parity)+left)%kC
+		if target == right {
+			return true
+		}
+	}
+	return false
+}
+
+// qualityString converts the proof into plot order and hashes the two x
+// values at qualityIndex with the challenge
+func qualityString(k uint32, proof []byte, qualityIndex uint64, challenge []byte) []byte {
+	kk := uint64(k)
+	xs := &bitString{data: proof, size: kk * 64}
+	for table := uint64(1); table < 7; table++ {
+		ordered := &bitString{}
+		size := kk << (table - 1)
+		for j := uint64(0); j < 1<<(7-table); j += 2 {
+			left := xs.slice(j*size, (j+1)*size)
+			right := xs.slice((j+1)*size, (j+2)*size)
+			if compareProofBits(left, right, kk) {
+				ordered.append(left)
+				ordered.append(right)
+			} else {
+				ordered.append(right)
+				ordered.append(left)
+			}
+		}
+		xs = ordered
+	}
+	input := &bitString{data: append([]byte{}, challenge[:32]...), size: 256}
+	input.appendBits(xs.data, kk*qualityIndex, 2*kk)
+	quality := sha256.Sum256(input.data)
+	return quality[:]
+}
+
+// compareProofBits reports whether left < right, comparing their k bit values
+// from the last one to the first
+func compareProofBits(left, right *bitString, k uint64) bool {
+	for i := int(left.size/k) - 1; i >= 0; i-- {
+		l := bitsAt(left.data, uint64(i)*k, k)
+		r := bitsAt(right.data, uint64(i)*k, k)
+		if l != r {
+			return l < r
+		}
+	}
+	return false
+}
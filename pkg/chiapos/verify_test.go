@@ -0,0 +1,48 @@
+package chiapos
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// TestVerifyProof compares VerifyProof with ValidateProofStatic, libchiapos
+// with cgo, on valid and corrupted proofs
+func TestVerifyProof(t *testing.T) {
+	reader, err := newPlotReader(testPlot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, k := reader.GetId(), reader.GetSize()
+	rng := rand.New(rand.NewSource(2))
+	verified := 0
+	for i, challenge := range challenges() {
+		qualities, _ := reader.GetQualitiesForChallenge(challenge)
+		for index, quality := range qualities {
+			proof, ok := reader.GetFullProof(challenge, index)
+			if !ok {
+				t.Fatalf("challenge %d proof %d not found", i, index)
+			}
+			got, ok := VerifyProof(id, k, challenge, proof)
+			if !ok || !bytes.Equal(got, quality) {
+				t.Fatalf("challenge %d proof %d: VerifyProof = %x %v, want %x", i, index, got, ok, quality)
+			}
+			want, wantOk := ValidateProofStatic(id, challenge, proof, k)
+			if !wantOk || !bytes.Equal(got, want) {
+				t.Fatalf("challenge %d proof %d: ValidateProofStatic = %x %v", i, index, want, wantOk)
+			}
+
+			corrupted := append([]byte{}, proof...)
+			bit := rng.Intn(len(corrupted) * 8)
+			corrupted[bit/8] ^= 1 << (bit % 8)
+			_, ok = VerifyProof(id, k, challenge, corrupted)
+			if _, wantOk := ValidateProofStatic(id, challenge, corrupted, k); ok != wantOk {
+				t.Fatalf("challenge %d proof %d bit %d: corrupted proof VerifyProof %v, ValidateProofStatic %v", i, index, bit, ok, wantOk)
+			}
+			verified++
+		}
+	}
+	if verified == 0 {
+		t.Fatal("no proofs verified")
+	}
+}
@@ -2,30 +2,228 @@ package config
 
 import (
 	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	Path []string `yaml:"path"`
 	Rpc  struct {
-		Url      string `yaml:"url"`
-		Username string `yaml:"username"`
-		Password string `yaml:"password"`
-		Token    string `yaml:"token"`
+		RpcEndpoint `yaml:",inline"`
+		Endpoints   []RpcEndpoint `yaml:"endpoints"` // more nodes to fail over to
+		SubmitAll   bool          `yaml:"submitAll"` // submit proofs to every healthy node
+		// StaleHeight blocks a node may be behind the best node before it is
+		// not used anymore, default 3
+		StaleHeight         int `yaml:"staleHeight"`
+		HealthCheckInterval int `yaml:"healthCheckInterval"` // seconds, default 10
+		PollInterval        int `yaml:"pollInterval"`        // milliseconds between mining info polls, default 1000
+		// Push new mining info pushed by the node, polling is used while it is not connected
+		Push struct {
+			Mode    string `yaml:"mode"`    // websocket, longpoll or empty to poll only
+			Url     string `yaml:"url"`     // websocket url, the long-poll uses the rpc endpoints
+			Timeout int    `yaml:"timeout"` // seconds a long-poll waits, default 30
+		}
 	}
 	Log struct {
 		Level string `yaml:"level"`
 		File  string `yaml:"file"`
 	}
-	FarmerKey        map[string]string `yaml:"farmerKey"`
-	FarmerPrivateKey []string          `yaml:"farmerPrivateKey"`
+	Scan struct {
+		DiskWorkers int `yaml:"diskWorkers"` // concurrent plot lookups per device, default 2
+		MaxWorkers  int `yaml:"maxWorkers"`  // concurrent plot lookups in total, default 16
+	}
+	Keystore struct {
+		File           string `yaml:"file"` // encrypted farmer keys, default keystore.json
+		PassphraseFile string `yaml:"passphraseFile"`
+	}
+	History struct {
+		File    string `yaml:"file"` // sqlite database, default history.db
+		Disable bool   `yaml:"disable"`
+	}
+	Api struct {
+		Listen string `yaml:"listen"` // e.g. 127.0.0.1:8090, empty disables the api
+	}
+	// Metrics serves /metrics on its own address, the api serves it too
+	Metrics struct {
+		Listen string `yaml:"listen"` // e.g. 127.0.0.1:9090, empty serves the metrics on the api only
+	}
+	// Pool pooled farming of the plots created for a pool contract, proofs
+	// meeting the chain difficulty are still submitted to the node
+	Pool struct {
+		Url                string `yaml:"url"`                // empty disables pooling
+		LauncherId         string `yaml:"launcherId"`         // hex launcher id of the pool contract
+		ContractPuzzleHash string `yaml:"contractPuzzleHash"` // hex, empty sends the partials of every contract plot
+		AuthenticationKey  string `yaml:"authenticationKey"`  // hex private key, default the farmer key of the plot
+	}
+	// Role miner (default) farms local plots, farmer also accepts remote
+	// harvesters, harvester scans local plots for a remote farmer
+	Role   string `yaml:"role"`
+	Farmer struct {
+		Listen string `yaml:"listen"` // grpc address harvesters connect to, default 127.0.0.1:8448
+		// Token shared secret of the harvesters, empty accepts any and is only
+		// allowed on a loopback address
+		Token    string `yaml:"token"`
+		CertFile string `yaml:"certFile"` // tls certificate, with keyFile enables tls
+		KeyFile  string `yaml:"keyFile"`
+	}
+	Harvester struct {
+		Farmer string `yaml:"farmer"` // farmer address, e.g. 192.168.1.10:8448
+		Name   string `yaml:"name"`   // default the host name
+		Token  string `yaml:"token"`
+		// CaFile certificate the farmer certificate is verified with, enables tls
+		CaFile     string `yaml:"caFile"`
+		ServerName string `yaml:"serverName"` // name in the farmer certificate, default the farmer host
+	}
+	FarmerKey          map[string]string `yaml:"farmerKey"`
+	FarmerPrivateKey   []string          `yaml:"farmerPrivateKey"`
+	PlotReloadInterval int               `yaml:"plotReloadInterval"` // seconds, 0 means default, negative disables
+	// MissingFarmerKey what to do with plots whose farmer key is not configured:
+	// warn (default), exclude them from farming or refuse to start. Harvesters
+	// have no farmer keys and ignore it, the farmer audits the keys they report
+	// and drops their qualities (exclude) or refuses the harvester (refuse).
+	MissingFarmerKey string `yaml:"missingFarmerKey"`
+	// PathPriority directories whose copy of a plot is farmed when the same plot
+	// is found more than once, first preferred, then the order of Path
+	PathPriority []string `yaml:"pathPriority"`
 }
 
-func (c *Config) GetAuthorizationToken() string {
-	if c.Rpc.Token != "" {
-		return c.Rpc.Token
+// RpcEndpoint json rpc endpoint of a node
+type RpcEndpoint struct {
+	Url      string `yaml:"url"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Token    string `yaml:"token"`
+	Priority int    `yaml:"priority"` // lower is preferred
+}
+
+func (e *RpcEndpoint) GetAuthorizationToken() string {
+	if e.Token != "" {
+		return e.Token
 	}
-	if c.Rpc.Username != "" || c.Rpc.Password != "" {
-		return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Rpc.Username+":"+c.Rpc.Password))
+	if e.Username != "" || e.Password != "" {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(e.Username+":"+e.Password))
 	}
 	return ""
 }
+
+// GetRpcEndpoints returns the configured nodes ordered by priority
+func (c *Config) GetRpcEndpoints() []RpcEndpoint {
+	var list []RpcEndpoint
+	if c.Rpc.Url != "" {
+		list = append(list, c.Rpc.RpcEndpoint)
+	}
+	list = append(list, c.Rpc.Endpoints...)
+	if len(list) == 0 {
+		list = append(list, RpcEndpoint{Url: "http://localhost:3332"})
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Priority < list[j].Priority
+	})
+	return list
+}
+
+// GetStaleHeight returns how many blocks a node may be behind the best node
+func (c *Config) GetStaleHeight() uint32 {
+	if c.Rpc.StaleHeight > 0 {
+		return uint32(c.Rpc.StaleHeight)
+	}
+	return 3
+}
+
+// GetPollInterval returns the milliseconds between two mining info polls
+func (c *Config) GetPollInterval() int {
+	if c.Rpc.PollInterval > 0 {
+		return c.Rpc.PollInterval
+	}
+	return 1000
+}
+
+// GetPushTimeout returns how long a long-poll waits for new mining info
+func (c *Config) GetPushTimeout() time.Duration {
+	if c.Rpc.Push.Timeout > 0 {
+		return time.Duration(c.Rpc.Push.Timeout) * time.Second
+	}
+	return 30 * time.Second
+}
+
+// GetHealthCheckInterval returns the seconds between the health checks of the nodes
+func (c *Config) GetHealthCheckInterval() int {
+	if c.Rpc.HealthCheckInterval > 0 {
+		return c.Rpc.HealthCheckInterval
+	}
+	return 10
+}
+
+// GetHistoryFile returns the history database file
+func (c *Config) GetHistoryFile() string {
+	if c.History.File != "" {
+		return c.History.File
+	}
+	return "history.db"
+}
+
+// Validate checks the values that are not free form
+func (c *Config) Validate() error {
+	switch c.MissingFarmerKey {
+	case "", "warn", "exclude", "refuse":
+	default:
+		return fmt.Errorf("unknown missingFarmerKey %v, want warn, exclude or refuse", c.MissingFarmerKey)
+	}
+	return nil
+}
+
+// ExcludeMissingFarmerKey reports whether plots without a configured farmer key are not farmed,
+// never on a harvester, it has no farmer keys
+func (c *Config) ExcludeMissingFarmerKey() bool {
+	if c.Role == "harvester" {
+		return false
+	}
+	return c.MissingFarmerKey == "exclude" || c.MissingFarmerKey == "refuse"
+}
+
+// GetKeystoreFile returns the keystore file
+func (c *Config) GetKeystoreFile() string {
+	if c.Keystore.File != "" {
+		return c.Keystore.File
+	}
+	return "keystore.json"
+}
+
+// GetHarvesterName returns the name the harvester reports to the farmer
+func (c *Config) GetHarvesterName() string {
+	if c.Harvester.Name != "" {
+		return c.Harvester.Name
+	}
+	name, err := os.Hostname()
+	if err != nil {
+		return "harvester"
+	}
+	return name
+}
+
+// GetFarmerListen returns the address the farmer accepts harvesters on
+func (c *Config) GetFarmerListen() string {
+	if c.Farmer.Listen != "" {
+		return c.Farmer.Listen
+	}
+	return "127.0.0.1:8448"
+}
+
+// GetPathPriority returns the priority of a plot file for duplicate plots, lower
+// is preferred: the first directory of PathPriority, then of Path, containing it
+func (c *Config) GetPathPriority(file string) int {
+	dirs := append(append([]string{}, c.PathPriority...), c.Path...)
+	for i, dir := range dirs {
+		// plot paths ending with /* are scanned recursively
+		dir = strings.TrimSuffix(strings.TrimSuffix(dir, "*"), "/")
+		rel, err := filepath.Rel(dir, file)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return i
+		}
+	}
+	return len(dirs)
+}
@@ -0,0 +1,154 @@
+package history
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/sirupsen/logrus"
+)
+
+const queueSize = 1024
+
+// Challenge a challenge received from the node
+type Challenge struct {
+	ID             uint      `gorm:"primary_key" json:"-"`
+	Height         uint32    `gorm:"index" json:"height"`
+	Challenge      string    `json:"challenge"`
+	Difficulty     uint64    `json:"difficulty"`
+	FilterBits     int       `json:"filter_bits"`
+	ScanIterations int64     `json:"scan_iterations"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+}
+
+// Candidate a quality found for a challenge
+type Candidate struct {
+	ID             uint      `gorm:"primary_key" json:"-"`
+	Height         uint32    `gorm:"index" json:"height"`
+	Challenge      string    `json:"challenge"`
+	ScanIterations int64     `json:"scan_iterations"`
+	PlotId         string    `gorm:"index" json:"plot_id"`
+	PlotFile       string    `json:"plot_file"`
+	ResponseNumber int32     `json:"response_number"`
+	RequiredIters  uint64    `json:"required_iters"`
+	Deadline       uint64    `json:"deadline"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+}
+
+// Submission the outcome of pos_submitSignedProof
+type Submission struct {
+	ID             uint      `gorm:"primary_key" json:"-"`
+	Height         uint32    `gorm:"index" json:"height"`
+	Challenge      string    `json:"challenge"`
+	ScanIterations int64     `json:"scan_iterations"`
+	PlotId         string    `gorm:"index" json:"plot_id"`
+	ResponseNumber int32     `json:"response_number"`
+	RequiredIters  uint64    `json:"required_iters"`
+	Accepted       bool      `json:"accepted"`
+	Message        string    `json:"message"`
+	Error          string    `json:"error"`
+	Code           int       `json:"code"`
+	Attempts       int       `json:"attempts"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+}
+
+// PlotDeadline best deadline of a plot
+type PlotDeadline struct {
+	PlotId     string `json:"plot_id"`
+	PlotFile   string `json:"plot_file"`
+	Deadline   uint64 `json:"deadline"`
+	Candidates int    `json:"candidates"`
+}
+
+// DB history database, records are written in the background
+type DB struct {
+	db    *gorm.DB
+	queue chan interface{}
+}
+
+// Open opens or creates the sqlite database
+func Open(file string) (*DB, error) {
+	db, err := gorm.Open("sqlite3", file)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.AutoMigrate(&Challenge{}, &Candidate{}, &Submission{}).Error; err != nil {
+		db.Close()
+		return nil, err
+	}
+	h := &DB{
+		db:    db,
+		queue: make(chan interface{}, queueSize),
+	}
+	go h.run()
+	return h, nil
+}
+
+func (h *DB) run() {
+	for record := range h.queue {
+		if err := h.db.Create(record).Error; err != nil {
+			logrus.Errorf("Failed to write history %v", err)
+		}
+	}
+}
+
+// add queues the record, it is dropped when the writer falls behind.
+// Adding to a nil DB is a no-op, so callers need not check whether history is enabled.
+func (h *DB) add(record interface{}) {
+	if h == nil {
+		return
+	}
+	select {
+	case h.queue <- record:
+	default:
+		logrus.Warnf("History queue is full, drop record %T", record)
+	}
+}
+
+// AddChallenge records a challenge
+func (h *DB) AddChallenge(challenge *Challenge) {
+	h.add(challenge)
+}
+
+// AddCandidate records a proof candidate
+func (h *DB) AddCandidate(candidate *Candidate) {
+	h.add(candidate)
+}
+
+// AddSubmission records a submission outcome
+func (h *DB) AddSubmission(submission *Submission) {
+	h.add(submission)
+}
+
+// Wins returns the accepted submissions between from and to
+func (h *DB) Wins(from, to time.Time) ([]*Submission, error) {
+	var list []*Submission
+	err := h.db.Where("accepted = ? AND created_at BETWEEN ? AND ?", true, from, to).
+		Order("created_at").Find(&list).Error
+	return list, err
+}
+
+// Failures returns the rejected and failed submissions between from and to
+func (h *DB) Failures(from, to time.Time) ([]*Submission, error) {
+	var list []*Submission
+	err := h.db.Where("accepted = ? AND created_at BETWEEN ? AND ?", false, from, to).
+		Order("created_at").Find(&list).Error
+	return list, err
+}
+
+// BestDeadlines returns the best deadline of every plot between from and to
+func (h *DB) BestDeadlines(from, to time.Time) ([]*PlotDeadline, error) {
+	var list []*PlotDeadline
+	err := h.db.Model(&Candidate{}).
+		Select("plot_id, plot_file, min(deadline) as deadline, count(*) as candidates").
+		Where("created_at BETWEEN ? AND ?", from, to).
+		Group("plot_id, plot_file").
+		Order("deadline").
+		Scan(&list).Error
+	return list, err
+}
+
+// Close closes the database, queued records are dropped
+func (h *DB) Close() error {
+	return h.db.Close()
+}
@@ -0,0 +1,236 @@
+package keystore
+
+import (
+	"chia-miner/pkg/bls"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	version   = 1
+	kdfScrypt = "scrypt"
+	cipherGcm = "aes-256-gcm"
+
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+var (
+	// ErrWrongPassphrase the passphrase does not decrypt the keystore
+	ErrWrongPassphrase = errors.New("wrong passphrase or corrupted keystore")
+	// ErrUnsupported the keystore was written with unknown parameters
+	ErrUnsupported = errors.New("unsupported keystore format")
+	// ErrKeyNotFound no key with the fingerprint
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrKeyExists the key is already in the keystore
+	ErrKeyExists = errors.New("key already exists")
+)
+
+// Entry a farmer key stored in the keystore
+type Entry struct {
+	Fingerprint uint32 `json:"fingerprint"`
+	PublicKey   string `json:"public_key"`
+	PrivateKey  string `json:"private_key"`
+	Label       string `json:"label"`
+	Created     int64  `json:"created"`
+}
+
+type kdfParams struct {
+	N int `json:"n"`
+	R int `json:"r"`
+	P int `json:"p"`
+}
+
+// file the encrypted keystore file, the plaintext is the json list of entries
+type file struct {
+	Version    int       `json:"version"`
+	Kdf        string    `json:"kdf"`
+	KdfParams  kdfParams `json:"kdf_params"`
+	Salt       string    `json:"salt"`
+	Cipher     string    `json:"cipher"`
+	Nonce      string    `json:"nonce"`
+	Ciphertext string    `json:"ciphertext"`
+}
+
+// Keystore farmer keys encrypted with a passphrase derived key
+type Keystore struct {
+	path       string
+	passphrase []byte
+	entries    []*Entry
+}
+
+// Exists reports whether the keystore file exists
+func Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Open decrypts the keystore, an empty keystore is returned if the file does not exist
+func Open(path string, passphrase []byte) (*Keystore, error) {
+	ks := &Keystore{
+		path:       path,
+		passphrase: passphrase,
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ks, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Version != version || f.Kdf != kdfScrypt || f.Cipher != cipherGcm {
+		return nil, ErrUnsupported
+	}
+	salt, err := hex.DecodeString(f.Salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(f.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := hex.DecodeString(f.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAead(passphrase, salt, f.KdfParams)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, ErrUnsupported
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	if err := json.Unmarshal(plaintext, &ks.entries); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+func newAead(passphrase, salt []byte, params kdfParams) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, params.N, params.R, params.P, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Save encrypts the keystore with a fresh salt and nonce and replaces the file
+func (ks *Keystore) Save() error {
+	plaintext, err := json.Marshal(ks.entries)
+	if err != nil {
+		return err
+	}
+	params := kdfParams{N: scryptN, R: scryptR, P: scryptP}
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	aead, err := newAead(ks.passphrase, salt, params)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(&file{
+		Version:    version,
+		Kdf:        kdfScrypt,
+		KdfParams:  params,
+		Salt:       hex.EncodeToString(salt),
+		Cipher:     cipherGcm,
+		Nonce:      hex.EncodeToString(nonce),
+		Ciphertext: hex.EncodeToString(aead.Seal(nil, nonce, plaintext, nil)),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(ks.path), filepath.Base(ks.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), ks.path)
+}
+
+// Add adds a private key, the keystore must be saved afterwards
+func (ks *Keystore) Add(privateKey *bls.PrivateKey, label string) (*Entry, error) {
+	publicKey := privateKey.Public().(*bls.PublicKey)
+	fingerprint := publicKey.GetFingerprint()
+	for _, entry := range ks.entries {
+		if entry.Fingerprint == fingerprint {
+			return nil, ErrKeyExists
+		}
+	}
+	privateKeyData, _ := privateKey.MarshalBinary()
+	publicKeyData, _ := publicKey.MarshalBinary()
+	entry := &Entry{
+		Fingerprint: fingerprint,
+		PublicKey:   hex.EncodeToString(publicKeyData),
+		PrivateKey:  hex.EncodeToString(privateKeyData),
+		Label:       label,
+		Created:     time.Now().Unix(),
+	}
+	ks.entries = append(ks.entries, entry)
+	return entry, nil
+}
+
+// Remove removes the key with the fingerprint, the keystore must be saved afterwards
+func (ks *Keystore) Remove(fingerprint uint32) error {
+	for i, entry := range ks.entries {
+		if entry.Fingerprint == fingerprint {
+			ks.entries = append(ks.entries[:i], ks.entries[i+1:]...)
+			return nil
+		}
+	}
+	return ErrKeyNotFound
+}
+
+// Get returns the key with the fingerprint
+func (ks *Keystore) Get(fingerprint uint32) (*Entry, error) {
+	for _, entry := range ks.entries {
+		if entry.Fingerprint == fingerprint {
+			return entry, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// List returns all keys
+func (ks *Keystore) List() []*Entry {
+	return append([]*Entry{}, ks.entries...)
+}
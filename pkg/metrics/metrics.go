@@ -0,0 +1,198 @@
+package metrics
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+const namespace = "miner"
+
+var (
+	// PlotsChecked plots checked against the filter
+	PlotsChecked = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "plots_checked_total",
+		Help:      "Number of plots checked against the plot filter.",
+	}, []string{"path"})
+
+	// PlotsPassed plots passing the filter
+	PlotsPassed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "plots_passed_filter_total",
+		Help:      "Number of plots passing the plot filter.",
+	}, []string{"path"})
+
+	// QualitiesFound qualities returned by the quality lookups
+	QualitiesFound = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "qualities_found_total",
+		Help:      "Number of qualities found for challenges.",
+	}, []string{"path"})
+
+	// ProofsFetched full proofs read from plots
+	ProofsFetched = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "proofs_fetched_total",
+		Help:      "Number of full proofs read from plots.",
+	}, []string{"path"})
+
+	// InvalidProofs full proofs failing verification, not submitted
+	InvalidProofs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invalid_proofs_total",
+		Help:      "Number of full proofs failing verification.",
+	}, []string{"device"})
+
+	// QualityLookupSeconds latency of GetQualitiesForChallenge
+	QualityLookupSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "quality_lookup_seconds",
+		Help:      "Latency of quality lookups.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30},
+	}, []string{"path"})
+
+	// FullProofSeconds latency of GetFullProof
+	FullProofSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "full_proof_seconds",
+		Help:      "Latency of full proof lookups.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30, 60},
+	}, []string{"path"})
+
+	// DeviceScanSeconds time spent on the plots of a device per scan
+	DeviceScanSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "device_scan_seconds",
+		Help:      "Time spent on the plots of a device per scan.",
+		Buckets:   []float64{0.1, 0.5, 1, 2, 5, 10, 30, 60, 120},
+	}, []string{"device"})
+
+	// RpcRequestSeconds latency of json rpc requests
+	RpcRequestSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_request_seconds",
+		Help:      "Latency of json rpc requests to the node.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	// RpcErrors failed json rpc requests, code is the rpc error code or the kind of failure
+	RpcErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_errors_total",
+		Help:      "Number of failed json rpc requests.",
+	}, []string{"method", "code"})
+
+	// RpcEndpointUp health of the node endpoints, 1 when the node is used for farming
+	RpcEndpointUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rpc_endpoint_up",
+		Help:      "Whether the node endpoint is healthy and not behind.",
+	}, []string{"url"})
+
+	// Submissions submitted proofs by result: accepted, rejected, failed or duplicate
+	Submissions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "submissions_total",
+		Help:      "Number of submitted proofs by result.",
+	}, []string{"result"})
+
+	// PoolPartials partials submitted to the pool by result: accepted, rejected or failed
+	PoolPartials = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pool_partials_total",
+		Help:      "Number of partial proofs submitted to the pool by result.",
+	}, []string{"result"})
+
+	// PoolDifficulty current pool difficulty
+	PoolDifficulty = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pool_difficulty",
+		Help:      "Current pool difficulty.",
+	})
+
+	// PoolPoints points of the farmer reported by the pool
+	PoolPoints = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pool_points",
+		Help:      "Points of the farmer reported by the pool.",
+	})
+
+	// Plots loaded plots
+	Plots = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "plots",
+		Help:      "Number of loaded plots.",
+	})
+
+	// SpaceBytes total raw space of the loaded plots, the expected plot size of
+	// their k sizes without the on-disk factor of the capacity
+	SpaceBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "space_bytes",
+		Help:      "Total raw space of the loaded plots in bytes, the expected plot size of their k sizes.",
+	})
+
+	// Height current height
+	Height = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "height",
+		Help:      "Current block height.",
+	})
+
+	// Difficulty current difficulty
+	Difficulty = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "difficulty",
+		Help:      "Current difficulty.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		PlotsChecked,
+		PlotsPassed,
+		QualitiesFound,
+		ProofsFetched,
+		InvalidProofs,
+		QualityLookupSeconds,
+		FullProofSeconds,
+		DeviceScanSeconds,
+		RpcRequestSeconds,
+		RpcErrors,
+		RpcEndpointUp,
+		Submissions,
+		PoolPartials,
+		PoolDifficulty,
+		PoolPoints,
+		Plots,
+		SpaceBytes,
+		Height,
+		Difficulty,
+	)
+}
+
+// Handler http handler exposing the metrics
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Start listens on address and serves /metrics in the background
+func Start(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			logrus.Errorf("metrics server stopped, error %v", err)
+		}
+	}()
+	logrus.Infof("metrics server listening on %v", listener.Addr())
+	return nil
+}
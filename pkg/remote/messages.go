@@ -0,0 +1,79 @@
+package remote
+
+// Challenge a new challenge to scan
+type Challenge struct {
+	Height         uint32 `json:"height"`
+	Challenge      []byte `json:"challenge"`
+	Difficulty     uint64 `json:"difficulty"`
+	FilterBits     int    `json:"filter_bits"`
+	ScanIterations int64  `json:"scan_iterations"`
+	// Rescan scan the challenge again even if it was scanned already
+	Rescan bool `json:"rescan,omitempty"`
+}
+
+// ProofRequest asks a harvester for the full proof of a quality
+type ProofRequest struct {
+	Id uint64 `json:"id"`
+	// Challenge the plot challenge, sha256(challenge || scan iterations)
+	Challenge []byte `json:"challenge"`
+	PlotId    []byte `json:"plot_id"`
+	Index     int    `json:"index"`
+	// Quality the quality the harvester reported, the proof must match it
+	Quality []byte `json:"quality"`
+}
+
+// FarmerMessage message sent by the farmer, exactly one field is set
+type FarmerMessage struct {
+	Challenge    *Challenge    `json:"challenge,omitempty"`
+	ProofRequest *ProofRequest `json:"proof_request,omitempty"`
+}
+
+// Hello first message of a harvester, sent again when its plots are reloaded
+type Hello struct {
+	Name     string      `json:"name"`
+	Plots    int         `json:"plots"`
+	Capacity uint64      `json:"capacity"`
+	RawSpace uint64      `json:"raw_space"`
+	Keys     []*PlotKeys `json:"keys"` // the farmer audits the farmer keys of the plots
+}
+
+// PlotKeys plots of a harvester with a farmer and pool public key, hex encoded
+type PlotKeys struct {
+	FarmerPublicKey string `json:"farmer_public_key"`
+	PoolPublicKey   string `json:"pool_public_key"` // or pool contract puzzle hash
+	Plots           int    `json:"plots"`
+	Capacity        uint64 `json:"capacity"`
+}
+
+// Quality a quality of a plot passing the filter
+type Quality struct {
+	Height         uint32 `json:"height"`
+	Challenge      []byte `json:"challenge"`
+	ScanIterations int64  `json:"scan_iterations"`
+	PlotId         []byte `json:"plot_id"`
+	PlotFile       string `json:"plot_file"`
+	Size           uint32 `json:"size"`
+	// the public keys of the plot memo, the local master secret stays on the
+	// harvester, the farmer checks they derive the plot id
+	PoolPublicKey          []byte `json:"pool_public_key,omitempty"`
+	PoolContractPuzzleHash []byte `json:"pool_contract_puzzle_hash,omitempty"`
+	FarmerPublicKey        []byte `json:"farmer_public_key"`
+	LocalPublicKey         []byte `json:"local_public_key"`
+	PlotPublicKey          []byte `json:"plot_public_key"`
+	Index                  int    `json:"index"`
+	Quality                []byte `json:"quality"`
+}
+
+// Proof answer to a ProofRequest
+type Proof struct {
+	Id    uint64 `json:"id"`
+	Proof []byte `json:"proof,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// HarvesterMessage message sent by a harvester, exactly one field is set
+type HarvesterMessage struct {
+	Hello   *Hello   `json:"hello,omitempty"`
+	Quality *Quality `json:"quality,omitempty"`
+	Proof   *Proof   `json:"proof,omitempty"`
+}
@@ -0,0 +1,127 @@
+// Package remote is the grpc protocol between a farmer and its remote harvesters.
+// Harvesters connect to the farmer and keep one bidirectional stream open: the
+// farmer sends challenges and proof requests, the harvester answers with the
+// qualities of the plots passing the filter and the requested proofs.
+// Messages are encoded as json, so no generated protobuf code is needed.
+package remote
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+const (
+	serviceName = "remote.Farmer"
+	connectName = "/remote.Farmer/Connect"
+	// TokenHeader metadata key of the shared harvester token
+	TokenHeader = "x-harvester-token"
+)
+
+// codec encodes the messages as json
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (codec) Name() string {
+	return "json"
+}
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
+
+// CallOption selects the json codec on the client
+func CallOption() grpc.CallOption {
+	return grpc.CallContentSubtype(codec{}.Name())
+}
+
+// FarmerServer is implemented by the farmer
+type FarmerServer interface {
+	Connect(stream FarmerConnectServer) error
+}
+
+// FarmerConnectServer farmer side of a harvester stream
+type FarmerConnectServer interface {
+	Send(*FarmerMessage) error
+	Recv() (*HarvesterMessage, error)
+	grpc.ServerStream
+}
+
+type farmerConnectServer struct {
+	grpc.ServerStream
+}
+
+func (s *farmerConnectServer) Send(m *FarmerMessage) error {
+	return s.ServerStream.SendMsg(m)
+}
+
+func (s *farmerConnectServer) Recv() (*HarvesterMessage, error) {
+	m := new(HarvesterMessage)
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func connectHandler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(FarmerServer).Connect(&farmerConnectServer{stream})
+}
+
+var farmerServiceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*FarmerServer)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Connect",
+			Handler:       connectHandler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+}
+
+// RegisterFarmerServer registers the farmer service
+func RegisterFarmerServer(s *grpc.Server, srv FarmerServer) {
+	s.RegisterService(&farmerServiceDesc, srv)
+}
+
+// FarmerConnectClient harvester side of the stream
+type FarmerConnectClient interface {
+	Send(*HarvesterMessage) error
+	Recv() (*FarmerMessage, error)
+	grpc.ClientStream
+}
+
+type farmerConnectClient struct {
+	grpc.ClientStream
+}
+
+func (c *farmerConnectClient) Send(m *HarvesterMessage) error {
+	return c.ClientStream.SendMsg(m)
+}
+
+func (c *farmerConnectClient) Recv() (*FarmerMessage, error) {
+	m := new(FarmerMessage)
+	if err := c.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Connect opens the harvester stream
+func Connect(ctx context.Context, conn *grpc.ClientConn) (FarmerConnectClient, error) {
+	stream, err := conn.NewStream(ctx, &farmerServiceDesc.Streams[0], connectName, CallOption())
+	if err != nil {
+		return nil, err
+	}
+	return &farmerConnectClient{stream}, nil
+}
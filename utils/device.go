@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package utils
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// GetDeviceId returns an identifier of the device storing the file
+func GetDeviceId(path string) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if stat, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return strconv.FormatUint(uint64(stat.Dev), 10), nil
+	}
+	return "", nil
+}
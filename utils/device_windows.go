@@ -0,0 +1,15 @@
+package utils
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// GetDeviceId returns an identifier of the device storing the file
+func GetDeviceId(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(filepath.VolumeName(absPath)), nil
+}
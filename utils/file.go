@@ -19,6 +19,18 @@ func PathExists(path string) bool {
 	return false
 }
 
+// CheckFileReadable checks that the file can be opened and read
+func CheckFileReadable(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var b [1]byte
+	_, err = f.Read(b[:])
+	return err
+}
+
 type FileList struct {
 	FilePath string
 	FileDir  string